@@ -2,9 +2,9 @@ package analyticsservice
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"strconv"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -32,8 +32,11 @@ const (
 	sdkLanguageAttribute         string = "SDK_LANGUAGE"
 	sdkLanguage                  string = "go"
 	globalTarget                 string = "global"
-	maxAnalyticsEntries          int    = 10000
-	maxTargetEntries             int    = 100000
+	// maxAnalyticsEntries and maxTargetEntries are the default number of entries sent in each request
+	maxAnalyticsEntries int = 10000
+	maxTargetEntries    int = 100000
+	// analyticsQueueSize is how many evaluations can be waiting to be aggregated before new ones are dropped
+	analyticsQueueSize int = 10000
 )
 
 // SafeAnalyticsCache is a type that provides thread safe access to maps used by analytics
@@ -65,17 +68,60 @@ type AnalyticsService struct {
 	evaluationAnalytics         SafeAnalyticsCache[string, analyticsEvent]
 	targetAnalytics             SafeAnalyticsCache[string, evaluation.Target]
 	seenTargets                 SafeSeenTargetsCache[string, bool]
-	logEvaluationLimitReached   atomic.Bool
-	logTargetLimitReached       atomic.Bool
+	maxEvaluationMetrics        int
+	maxTargetMetrics            int
 	timeout                     time.Duration
 	logger                      logger.Logger
-	metricsClient               metricsclient.ClientWithResponsesInterface
 	environmentID               string
 	seenTargetsClearingInterval time.Duration
+	// dropped counts the evaluations that couldn't be queued, reportedDrops is how many have been logged
+	dropped       atomic.Uint64
+	reportedDrops uint64
+	stop          chan struct{}
+	// spool holds the payloads that failed to send until they're retried or older than spoolMaxAge
+	spool       Spool
+	spoolMaxAge time.Duration
+	// exporters receive every payload, harness is the one that sends them to the Feature Flag service and is
+	// nil until Start is given a metrics client
+	exporters []Exporter
+	harness   *harnessExporter
+	started   bool
+	// wg tracks the listener and the goroutines started by Start
+	wg sync.WaitGroup
 }
 
-// NewAnalyticsService creates and starts a analytics service to send data to the client
+// NewAnalyticsService creates and starts a analytics service to send data to the client. Metrics are sent every
+// timeout, which is limited to between a minute and an hour.
 func NewAnalyticsService(timeout time.Duration, logger logger.Logger, seenTargetsMaxSize int, seenTargetsClearingSchedule time.Duration) *AnalyticsService {
+	return NewAnalyticsServiceWithOptions(timeout, logger, seenTargetsMaxSize, seenTargetsClearingSchedule)
+}
+
+// Option configures an AnalyticsService created with NewAnalyticsServiceWithOptions
+type Option func(as *AnalyticsService)
+
+// WithMaxEvaluationMetrics sets the most evaluation metrics sent in a single request, larger payloads are split
+// across several requests. The default is 10000.
+func WithMaxEvaluationMetrics(max int) Option {
+	return func(as *AnalyticsService) {
+		if max > 0 {
+			as.maxEvaluationMetrics = max
+		}
+	}
+}
+
+// WithMaxTargetMetrics sets the most target metrics sent in a single request, larger payloads are split across
+// several requests. The default is 100000.
+func WithMaxTargetMetrics(max int) Option {
+	return func(as *AnalyticsService) {
+		if max > 0 {
+			as.maxTargetMetrics = max
+		}
+	}
+}
+
+// NewAnalyticsServiceWithOptions creates and starts a analytics service like NewAnalyticsService, configured
+// with options
+func NewAnalyticsServiceWithOptions(timeout time.Duration, logger logger.Logger, seenTargetsMaxSize int, seenTargetsClearingSchedule time.Duration, options ...Option) *AnalyticsService {
 	serviceTimeout := timeout
 	if timeout < 60*time.Second {
 		serviceTimeout = 60 * time.Second
@@ -83,43 +129,118 @@ func NewAnalyticsService(timeout time.Duration, logger logger.Logger, seenTarget
 		serviceTimeout = 1 * time.Hour
 	}
 	as := AnalyticsService{
-		analyticsChan:               make(chan analyticsEvent),
+		analyticsChan:               make(chan analyticsEvent, analyticsQueueSize),
 		evaluationAnalytics:         newSafeEvaluationAnalytics(),
 		targetAnalytics:             newSafeTargetAnalytics(),
 		seenTargets:                 newSafeSeenTargets(seenTargetsMaxSize),
+		maxEvaluationMetrics:        maxAnalyticsEntries,
+		maxTargetMetrics:            maxTargetEntries,
 		timeout:                     serviceTimeout,
 		logger:                      logger,
 		seenTargetsClearingInterval: seenTargetsClearingSchedule,
+		stop:                        make(chan struct{}),
+	}
+	for _, opt := range options {
+		opt(&as)
 	}
+	as.wg.Add(1)
 	go as.listener()
 
 	return &as
 }
 
-// Start starts the client and timer to send analytics
+// SetSpool retries payloads that fail to send at each analytics interval, backing off exponentially, until
+// they're older than maxAge. It must be called before Start.
+func (as *AnalyticsService) SetSpool(spool Spool, maxAge time.Duration) {
+	if maxAge <= 0 {
+		maxAge = defaultSpoolMaxAge
+	}
+	as.spool = spool
+	as.spoolMaxAge = maxAge
+}
+
+// AddExporter sends the metrics to exporter, whether or not they're sent to the Feature Flag service. It must be
+// called before Start.
+func (as *AnalyticsService) AddExporter(exporter Exporter) {
+	as.exporters = append(as.exporters, exporter)
+}
+
+// Start starts the timer to send analytics. The metrics are sent to the Feature Flag service using client, and
+// to the exporters added with AddExporter. If client is nil they're only sent to the exporters.
 func (as *AnalyticsService) Start(ctx context.Context, client metricsclient.ClientWithResponsesInterface, environmentID string) {
 	as.logger.Infof("%s Metrics started", sdk_codes.MetricsStarted)
-	as.metricsClient = client
+	if client != nil {
+		as.setMetricsClient(client)
+	}
 	as.environmentID = environmentID
+	as.started = true
+	as.wg.Add(2)
 	go as.startTimer(ctx)
 	go as.startSeenTargetsClearingSchedule(ctx, as.seenTargetsClearingInterval)
 }
 
+// setMetricsClient registers the exporter that sends metrics to the Feature Flag service using client, ahead of
+// the exporters added with AddExporter
+func (as *AnalyticsService) setMetricsClient(client metricsclient.ClientWithResponsesInterface) {
+	as.harness = &harnessExporter{client: client, logger: as.logger}
+	as.exporters = append([]Exporter{as.harness}, as.exporters...)
+}
+
+// Flush sends the metrics that haven't been sent yet, including evaluations still waiting in the queue.
+// It's used when the client is closed: the context passed to Start must already be cancelled, Flush
+// waits for the analytics goroutines to exit so the metrics aren't modified while they're sent. If Start
+// was never called, e.g. because the client didn't authenticate, the metrics are only sent to the exporters.
+func (as *AnalyticsService) Flush(ctx context.Context) error {
+	if len(as.exporters) == 0 {
+		return nil
+	}
+	if !as.started {
+		close(as.stop)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		as.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	for drained := false; !drained; {
+		select {
+		case ad := <-as.analyticsChan:
+			as.record(ad)
+		default:
+			drained = true
+		}
+	}
+
+	timeStamp := time.Now().UnixNano() / (int64(time.Millisecond) / int64(time.Nanosecond))
+	as.sendDataAndResetCache(ctx, timeStamp)
+	return ctx.Err()
+}
+
 func (as *AnalyticsService) startTimer(ctx context.Context) {
+	defer as.wg.Done()
 	for {
 		select {
 		case <-time.After(as.timeout):
 			timeStamp := time.Now().UnixNano() / (int64(time.Millisecond) / int64(time.Nanosecond))
 			as.sendDataAndResetCache(ctx, timeStamp)
 		case <-ctx.Done():
-			close(as.analyticsChan)
+			// the queue isn't closed as evaluations may still be pushing to it
+			close(as.stop)
 			as.logger.Infof("%s Metrics stopped", sdk_codes.MetricsStopped)
 			return
 		}
 	}
 }
 
-// PushToQueue is used to queue analytics data to send to the server
+// PushToQueue is used to queue analytics data to send to the server. It's called during every evaluation
+// so it never blocks, if the queue is full the event is dropped and counted instead.
 func (as *AnalyticsService) PushToQueue(featureConfig *rest.FeatureConfig, target *evaluation.Target, variation *rest.Variation) {
 
 	ad := analyticsEvent{
@@ -127,60 +248,69 @@ func (as *AnalyticsService) PushToQueue(featureConfig *rest.FeatureConfig, targe
 		featureConfig: featureConfig,
 		variation:     variation,
 	}
-	as.analyticsChan <- ad
+	select {
+	case as.analyticsChan <- ad:
+	default:
+		as.dropped.Add(1)
+	}
+}
+
+// DroppedEvents returns how many evaluations have been dropped because the analytics queue was full
+func (as *AnalyticsService) DroppedEvents() uint64 {
+	return as.dropped.Load()
 }
 
 func (as *AnalyticsService) listener() {
+	defer as.wg.Done()
 	as.logger.Info("Analytics cache successfully initialized")
-	for ad := range as.analyticsChan {
-		analyticsKey := getEvaluationAnalyticKey(ad)
-
-		// Check if we've hit capacity for evaluations
-		if as.evaluationAnalytics.size() < maxAnalyticsEntries {
-			// Update evaluation metrics
-			analytic, ok := as.evaluationAnalytics.get(analyticsKey)
+	for {
+		select {
+		case <-as.stop:
+			return
+		case ad, ok := <-as.analyticsChan:
 			if !ok {
-				ad.count = 1
-				as.evaluationAnalytics.set(analyticsKey, ad)
-			} else {
-				ad.count = analytic.count + 1
-				as.evaluationAnalytics.set(analyticsKey, ad)
-			}
-		} else {
-			if !as.logEvaluationLimitReached.Load() {
-				as.logger.Warnf("%s Evaluation analytic cache reached max size, remaining evaluation metrics for this analytics interval will not be sent", sdk_codes.EvaluationMetricsMaxSizeReached)
-				as.logEvaluationLimitReached.Store(true)
+				return
 			}
+			as.record(ad)
 		}
+	}
+}
 
-		// Check if target is nil or anonymous
-		if ad.target == nil || (ad.target.Anonymous != nil && *ad.target.Anonymous) {
-			continue
-		}
+// record aggregates an evaluation into the evaluation and target metrics
+func (as *AnalyticsService) record(ad analyticsEvent) {
+	analyticsKey := getEvaluationAnalyticKey(ad)
 
-		// Check if target has been seen
-		if _, seen := as.seenTargets.get(ad.target.Identifier); seen {
-			continue
-		}
+	// Update evaluation metrics. They're never dropped, if there are more than maxEvaluationMetrics they're
+	// sent in several requests.
+	analytic, ok := as.evaluationAnalytics.get(analyticsKey)
+	if !ok {
+		ad.count = 1
+		as.evaluationAnalytics.set(analyticsKey, ad)
+	} else {
+		ad.count = analytic.count + 1
+		as.evaluationAnalytics.set(analyticsKey, ad)
+	}
 
-		// Check if seen targets limit has been hit
-		if as.seenTargets.isLimitExceeded() {
-			continue
-		}
+	// Check if target is nil or anonymous
+	if ad.target == nil || (ad.target.Anonymous != nil && *ad.target.Anonymous) {
+		return
+	}
 
-		// Update seen targets
-		as.seenTargets.set(ad.target.Identifier, true)
+	// Check if target has been seen
+	if _, seen := as.seenTargets.get(ad.target.Identifier); seen {
+		return
+	}
 
-		// Update target metrics
-		if as.targetAnalytics.size() < maxTargetEntries {
-			as.targetAnalytics.set(ad.target.Identifier, *ad.target)
-		} else {
-			if !as.logTargetLimitReached.Load() {
-				as.logger.Warnf("%s Target analytics cache reached max size, remaining target metrics for this analytics interval will not be sent", sdk_codes.TargetMetricsMaxSizeReached)
-				as.logTargetLimitReached.Store(true)
-			}
-		}
+	// Check if seen targets limit has been hit
+	if as.seenTargets.isLimitExceeded() {
+		return
 	}
+
+	// Update seen targets
+	as.seenTargets.set(ad.target.Identifier, true)
+
+	// Update target metrics, the number of targets is bounded by the seen targets cache
+	as.targetAnalytics.set(ad.target.Identifier, *ad.target)
 }
 
 func convertInterfaceToString(i interface{}) string {
@@ -225,9 +355,10 @@ func (as *AnalyticsService) sendDataAndResetCache(ctx context.Context, timeStamp
 	targetAnalyticsClone := as.targetAnalytics
 	as.targetAnalytics = newSafeTargetAnalytics()
 
-	// Reset flags that keep track of cache limits being reached for logging purposes
-	as.logEvaluationLimitReached.Store(false)
-	as.logTargetLimitReached.Store(false)
+	if dropped := as.dropped.Load(); dropped > as.reportedDrops {
+		as.logger.Warnf("%d evaluation metrics were dropped since the last analytics interval because the analytics queue was full", dropped-as.reportedDrops)
+		as.reportedDrops = dropped
+	}
 
 	// Process evaluation metrics
 	metricData := as.processEvaluationMetrics(evaluationAnalyticsClone, timeStamp)
@@ -235,47 +366,130 @@ func (as *AnalyticsService) sendDataAndResetCache(ctx context.Context, timeStamp
 	// Process target metrics
 	targetData := as.processTargetMetrics(targetAnalyticsClone)
 
-	analyticsPayload := metricsclient.PostMetricsJSONRequestBody{
-		MetricsData: &metricData,
-		TargetData:  &targetData,
-	}
+	// retry the payloads that failed to send before sending this interval's
+	now := time.Now()
+	as.retrySpooled(ctx, now)
 
-	if as.metricsClient != nil {
-		emptyMetricsData := len(metricData) == 0
-		emptyTargetData := len(targetData) == 0
+	// if we have no metrics to send skip the post request
+	if len(metricData) == 0 && len(targetData) == 0 {
+		as.logger.Debug("No metrics or target data to send")
+		return
+	}
 
-		// if we have no metrics to send skip the post request
-		if emptyMetricsData && emptyTargetData {
-			as.logger.Debug("No metrics or target data to send")
-			return
+	for _, analyticsPayload := range splitPayload(metricData, targetData, as.maxEvaluationMetrics, as.maxTargetMetrics) {
+		for _, exporter := range as.exporters {
+			err := exporter.Export(ctx, as.environmentID, *analyticsPayload.MetricsData, *analyticsPayload.TargetData)
+			if err == nil {
+				continue
+			}
+			if exporter != Exporter(as.harness) {
+				as.logger.Warnf("Analytics exporter failed: %v", err)
+				continue
+			}
+			as.logger.Warn(err)
+			as.spoolPayload(SpooledPayload{Environment: as.environmentID, Payload: analyticsPayload, Created: now, Attempts: 1}, now)
 		}
+	}
+}
 
-		mClient := as.metricsClient
+// retrySpooled sends the spooled payloads that are due to be retried, and drops those older than the max age.
+// A payload is only removed from the spool once it's been sent, dropped or added again with its next
+// attempt, so a payload being retried when the process exits is retried again rather than lost.
+func (as *AnalyticsService) retrySpooled(ctx context.Context, now time.Time) {
+	if as.spool == nil || as.harness == nil {
+		return
+	}
+	payloads, err := as.spool.List()
+	if err != nil {
+		as.logger.Warnf("Unable to read the metrics spool: %v", err)
+	}
 
-		jsonData, err := json.Marshal(analyticsPayload)
-		if err != nil {
-			as.logger.Errorf(err.Error())
+	for _, payload := range payloads {
+		expired := now.Sub(payload.Created) > as.spoolMaxAge
+		if !expired && now.Before(payload.NextAttempt) {
+			continue
 		}
-		as.logger.Debug(string(jsonData))
 
-		resp, err := mClient.PostMetricsWithResponse(ctx, metricsclient.EnvironmentPathParam(as.environmentID), nil, analyticsPayload)
-		if err != nil {
-			as.logger.Warn(err)
-			return
-		}
-		if resp == nil {
-			as.logger.Warn("Empty response from metrics server")
-			return
+		if expired {
+			as.logger.Warnf("%s Dropping metrics that couldn't be sent within %v after %d attempts", sdk_codes.MetricsSendFail, as.spoolMaxAge, payload.Attempts)
+		} else if err := as.harness.Export(ctx, payload.Environment, payloadMetrics(payload.Payload), payloadTargets(payload.Payload)); err != nil {
+			as.logger.Warnf("Retrying metrics failed after %d attempts: %v", payload.Attempts, err)
+			retry := payload
+			retry.Attempts++
+			// keep the payload as it is if the retry can't be spooled, it's retried at the next interval
+			if !as.spoolPayload(retry, now) {
+				continue
+			}
+		} else {
+			as.logger.Debugf("%s Spooled metrics sent to server after %d attempts", sdk_codes.MetricsSendSuccess, payload.Attempts)
 		}
-		if resp.StatusCode() != 200 {
-			as.logger.Warnf("%s Non 200 response from metrics server: %d", sdk_codes.MetricsSendFail, resp.StatusCode())
-			return
+		if err := as.spool.Remove(payload); err != nil {
+			as.logger.Warnf("Unable to remove metrics from the spool: %v", err)
 		}
+	}
+}
 
-		as.logger.Debugf("%s Metrics sent to server", sdk_codes.MetricsSendSuccess)
-	} else {
-		as.logger.Warn("metrics client is not set")
+// spoolPayload adds a payload that failed to send at failedAt to the spool, scheduling the next attempt after
+// a backoff that doubles with every attempt. It returns false if the payload couldn't be spooled.
+func (as *AnalyticsService) spoolPayload(payload SpooledPayload, failedAt time.Time) bool {
+	if as.spool == nil {
+		return false
+	}
+	// the first attempt is the send that failed, a payload with no attempts recorded counts as one
+	if payload.Attempts < 1 {
+		payload.Attempts = 1
+	}
+	backoff := maxSpoolBackoff
+	if payload.Attempts <= 10 {
+		backoff = min(as.timeout<<(payload.Attempts-1), maxSpoolBackoff)
+	}
+	payload.NextAttempt = failedAt.Add(backoff)
+	if err := as.spool.Add(payload); err != nil {
+		as.logger.Warnf("Unable to spool metrics to retry them: %v", err)
+		return false
+	}
+	return true
+}
+
+// payloadMetrics returns the evaluation metrics held in a payload
+func payloadMetrics(payload metricsclient.PostMetricsJSONRequestBody) []metricsclient.MetricsData {
+	if payload.MetricsData == nil {
+		return nil
+	}
+	return *payload.MetricsData
+}
+
+// payloadTargets returns the target metrics held in a payload
+func payloadTargets(payload metricsclient.PostMetricsJSONRequestBody) []metricsclient.TargetData {
+	if payload.TargetData == nil {
+		return nil
+	}
+	return *payload.TargetData
+}
+
+// splitPayload splits the metrics into payloads holding at most maxMetrics evaluation metrics and maxTargets
+// target metrics, so large intervals are sent in several requests rather than dropped
+func splitPayload(metricData []metricsclient.MetricsData, targetData []metricsclient.TargetData, maxMetrics int, maxTargets int) []metricsclient.PostMetricsJSONRequestBody {
+	if maxMetrics <= 0 {
+		maxMetrics = maxAnalyticsEntries
+	}
+	if maxTargets <= 0 {
+		maxTargets = maxTargetEntries
+	}
+
+	var payloads []metricsclient.PostMetricsJSONRequestBody
+	for len(metricData) > 0 || len(targetData) > 0 {
+		metrics := metricData[:min(len(metricData), maxMetrics)]
+		targets := targetData[:min(len(targetData), maxTargets)]
+		metricData = metricData[len(metrics):]
+		targetData = targetData[len(targets):]
+
+		payloads = append(payloads, metricsclient.PostMetricsJSONRequestBody{
+			MetricsData: &metrics,
+			TargetData:  &targets,
+		})
 	}
+	return payloads
 }
 
 func (as *AnalyticsService) processEvaluationMetrics(evaluationAnalytics SafeAnalyticsCache[string, analyticsEvent], timeStamp int64) []metricsclient.MetricsData {
@@ -328,6 +542,7 @@ func (as *AnalyticsService) processTargetMetrics(targetAnalytics SafeAnalyticsCa
 }
 
 func (as *AnalyticsService) startSeenTargetsClearingSchedule(ctx context.Context, clearingInterval time.Duration) {
+	defer as.wg.Done()
 	ticker := time.NewTicker(clearingInterval)
 
 	for {
@@ -2,6 +2,8 @@ package analyticsservice
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"testing"
@@ -16,16 +18,29 @@ import (
 
 type MockMetricsClient struct {
 	LastBody  metricsclient.PostMetricsJSONRequestBody
+	Bodies    []metricsclient.PostMetricsJSONRequestBody
 	CallCount int
+	// StatusCode is returned for each request, 200 if it isn't set
+	StatusCode int
+	// OnPost is called before each request is answered, if it's set
+	OnPost func()
 }
 
 func (c *MockMetricsClient) PostMetricsWithResponse(ctx context.Context, environmentUUID metricsclient.EnvironmentPathParam, params *metricsclient.PostMetricsParams, body metricsclient.PostMetricsJSONRequestBody, reqEditors ...metricsclient.RequestEditorFn) (*metricsclient.PostMetricsResponse, error) {
 	c.LastBody = body
+	c.Bodies = append(c.Bodies, body)
 	c.CallCount++
+	if c.OnPost != nil {
+		c.OnPost()
+	}
 
+	statusCode := c.StatusCode
+	if statusCode == 0 {
+		statusCode = 200
+	}
 	return &metricsclient.PostMetricsResponse{
 		HTTPResponse: &http.Response{
-			StatusCode: 200,
+			StatusCode: statusCode,
 		},
 	}, nil
 }
@@ -124,6 +139,7 @@ func TestListenerHandlesEventsCorrectly(t *testing.T) {
 			defer close(service.analyticsChan)
 
 			// Start the listener in a goroutine
+			service.wg.Add(1)
 			go service.listener()
 
 			// Send all events for the test case
@@ -461,9 +477,9 @@ func TestSendDataAndResetCache(t *testing.T) {
 		evaluationAnalytics: evaluationAnalytics,
 		targetAnalytics:     targetAnalytics,
 		logger:              noOpLogger,
-		metricsClient:       mClient,
 		environmentID:       "test-env",
 	}
+	service.setMetricsClient(mClient)
 
 	ctx := context.Background()
 
@@ -532,6 +548,351 @@ func TestSendDataAndResetCache(t *testing.T) {
 	}
 }
 
+func TestSendDataAndResetCacheSplitsPayload(t *testing.T) {
+	evaluationAnalytics := newSafeEvaluationAnalytics()
+	for i := 0; i < 5; i++ {
+		feature := fmt.Sprintf("feature%d", i)
+		evaluationAnalytics.set(feature, analyticsEvent{
+			featureConfig: &rest.FeatureConfig{Feature: feature},
+			variation:     &rest.Variation{Identifier: "var1", Value: "value1"},
+			count:         1,
+		})
+	}
+	targetAnalytics := newSafeTargetAnalytics()
+	for i := 0; i < 3; i++ {
+		identifier := fmt.Sprintf("target%d", i)
+		targetAnalytics.set(identifier, evaluation.Target{Identifier: identifier})
+	}
+
+	mClient := &MockMetricsClient{}
+	service := AnalyticsService{
+		evaluationAnalytics:  evaluationAnalytics,
+		targetAnalytics:      targetAnalytics,
+		maxEvaluationMetrics: 2,
+		maxTargetMetrics:     2,
+		logger:               logger.NewNoOpLogger(),
+		environmentID:        "test-env",
+	}
+	service.setMetricsClient(mClient)
+	service.sendDataAndResetCache(context.Background(), 1715600410545)
+
+	assert.Equal(t, 3, mClient.CallCount)
+	var metrics, targets []string
+	for i, body := range mClient.Bodies {
+		assert.LessOrEqual(t, len(*body.MetricsData), 2, "request %d", i)
+		assert.LessOrEqual(t, len(*body.TargetData), 2, "request %d", i)
+		for _, m := range *body.MetricsData {
+			for _, attr := range m.Attributes {
+				if attr.Key == featureIdentifierAttribute {
+					metrics = append(metrics, attr.Value)
+				}
+			}
+		}
+		for _, td := range *body.TargetData {
+			targets = append(targets, td.Identifier)
+		}
+	}
+
+	// nothing is dropped
+	assert.ElementsMatch(t, []string{"feature0", "feature1", "feature2", "feature3", "feature4"}, metrics)
+	assert.ElementsMatch(t, []string{"target0", "target1", "target2"}, targets)
+}
+
+func TestSendDataAndResetCacheRetriesSpooledPayloads(t *testing.T) {
+	mClient := &MockMetricsClient{StatusCode: 503}
+	spool := NewMemorySpool(10)
+	service := AnalyticsService{
+		evaluationAnalytics: newSafeEvaluationAnalytics(),
+		targetAnalytics:     newSafeTargetAnalytics(),
+		logger:              logger.NewNoOpLogger(),
+		environmentID:       "test-env",
+		timeout:             time.Minute,
+	}
+	service.setMetricsClient(mClient)
+	service.SetSpool(spool, time.Hour)
+
+	service.evaluationAnalytics.set("key1", analyticsEvent{
+		featureConfig: &rest.FeatureConfig{Feature: "feature1"},
+		variation:     &rest.Variation{Identifier: "var1", Value: "value1"},
+		count:         3,
+	})
+	service.sendDataAndResetCache(context.Background(), 0)
+	assert.Equal(t, 1, mClient.CallCount)
+
+	payloads, _ := spool.List()
+	if !assert.Len(t, payloads, 1) {
+		return
+	}
+	assert.Equal(t, "test-env", payloads[0].Environment)
+	assert.Equal(t, 1, payloads[0].Attempts)
+	assert.WithinDuration(t, payloads[0].Created.Add(time.Minute), payloads[0].NextAttempt, time.Second)
+
+	t.Log("When the payload isn't due to be retried it stays in the spool")
+	service.sendDataAndResetCache(context.Background(), 0)
+	assert.Equal(t, 1, mClient.CallCount)
+	listed, _ := spool.List()
+	assert.Equal(t, payloads, listed)
+
+	t.Log("When the retry fails the backoff doubles and only the rescheduled payload is kept")
+	payloads = takeSpooled(t, spool)
+	payloads[0].NextAttempt = time.Time{}
+	assert.Nil(t, spool.Add(payloads[0]))
+	service.sendDataAndResetCache(context.Background(), 0)
+	assert.Equal(t, 2, mClient.CallCount)
+	payloads = takeSpooled(t, spool)
+	if !assert.Len(t, payloads, 1) {
+		return
+	}
+	assert.Equal(t, 2, payloads[0].Attempts)
+	assert.WithinDuration(t, time.Now().Add(2*time.Minute), payloads[0].NextAttempt, time.Second)
+
+	t.Log("When the retry succeeds the payload is removed from the spool")
+	mClient.StatusCode = 200
+	payloads[0].NextAttempt = time.Time{}
+	assert.Nil(t, spool.Add(payloads[0]))
+	service.sendDataAndResetCache(context.Background(), 0)
+	assert.Equal(t, 3, mClient.CallCount)
+	assert.Equal(t, 3, (*mClient.LastBody.MetricsData)[0].Count)
+	payloads, _ = spool.List()
+	assert.Empty(t, payloads)
+
+	t.Log("When a payload is older than the max age it's dropped")
+	assert.Nil(t, spool.Add(SpooledPayload{Environment: "test-env", Created: time.Now().Add(-2 * time.Hour)}))
+	service.sendDataAndResetCache(context.Background(), 0)
+	assert.Equal(t, 3, mClient.CallCount)
+	payloads, _ = spool.List()
+	assert.Empty(t, payloads)
+}
+
+func TestSpoolPayloadWithoutAttempts(t *testing.T) {
+	spool := NewMemorySpool(10)
+	service := AnalyticsService{logger: logger.NewNoOpLogger(), timeout: time.Minute}
+	service.SetSpool(spool, time.Hour)
+
+	t.Log("When a payload with no attempts is spooled it's scheduled as if it had been attempted once")
+	now := time.Now()
+	assert.True(t, service.spoolPayload(SpooledPayload{Environment: "test-env", Created: now, Attempts: 0}, now))
+	payloads, _ := spool.List()
+	if assert.Len(t, payloads, 1) {
+		assert.Equal(t, 1, payloads[0].Attempts)
+		assert.Equal(t, now.Add(time.Minute), payloads[0].NextAttempt)
+	}
+}
+
+func TestSendDataAndResetCacheKeepsSpooledPayloadsUntilSent(t *testing.T) {
+	dir := t.TempDir()
+	spool, err := NewDiskSpool(dir, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, spool.Add(SpooledPayload{Environment: "test-env", Created: time.Now(), Attempts: 1}))
+
+	// the payload is read by a second spool while it's being sent, as it would be if the process exited
+	// during the retry and started again
+	var spooledDuringSend []SpooledPayload
+	mClient := &MockMetricsClient{StatusCode: 503}
+	mClient.OnPost = func() {
+		restarted, err := NewDiskSpool(dir, 10)
+		assert.Nil(t, err)
+		spooledDuringSend, err = restarted.List()
+		assert.Nil(t, err)
+	}
+	service := AnalyticsService{
+		evaluationAnalytics: newSafeEvaluationAnalytics(),
+		targetAnalytics:     newSafeTargetAnalytics(),
+		logger:              logger.NewNoOpLogger(),
+		environmentID:       "test-env",
+		timeout:             time.Minute,
+	}
+	service.setMetricsClient(mClient)
+	service.SetSpool(spool, time.Hour)
+
+	service.sendDataAndResetCache(context.Background(), 0)
+	assert.Equal(t, 1, mClient.CallCount)
+	assert.Len(t, spooledDuringSend, 1)
+
+	t.Log("When the retry fails the payload is replaced by the rescheduled one")
+	payloads, err := spool.List()
+	assert.Nil(t, err)
+	if assert.Len(t, payloads, 1) {
+		assert.Equal(t, 2, payloads[0].Attempts)
+	}
+
+	t.Log("When the retry succeeds the payload is removed")
+	mClient.StatusCode = 200
+	payloads[0].NextAttempt = time.Time{}
+	assert.Nil(t, spool.Remove(payloads[0]))
+	assert.Nil(t, spool.Add(payloads[0]))
+	service.sendDataAndResetCache(context.Background(), 0)
+	assert.Equal(t, 2, mClient.CallCount)
+	assert.Len(t, spooledDuringSend, 1)
+	payloads, err = spool.List()
+	assert.Nil(t, err)
+	assert.Empty(t, payloads)
+}
+
+// recordingExporter records the batches it receives, and fails if err is set
+type recordingExporter struct {
+	environments []string
+	metrics      [][]metricsclient.MetricsData
+	targets      [][]metricsclient.TargetData
+	err          error
+}
+
+func (e *recordingExporter) Export(ctx context.Context, environmentID string, metrics []metricsclient.MetricsData, targets []metricsclient.TargetData) error {
+	e.environments = append(e.environments, environmentID)
+	e.metrics = append(e.metrics, metrics)
+	e.targets = append(e.targets, targets)
+	return e.err
+}
+
+func TestSendDataAndResetCacheExporters(t *testing.T) {
+	mClient := &MockMetricsClient{}
+	service := AnalyticsService{
+		evaluationAnalytics:  newSafeEvaluationAnalytics(),
+		targetAnalytics:      newSafeTargetAnalytics(),
+		maxEvaluationMetrics: 1,
+		logger:               logger.NewNoOpLogger(),
+		environmentID:        "test-env",
+	}
+	service.setMetricsClient(mClient)
+	failing := &recordingExporter{err: errors.New("unavailable")}
+	exporter := &recordingExporter{}
+	service.AddExporter(failing)
+	service.AddExporter(exporter)
+
+	for _, feature := range []string{"feature1", "feature2"} {
+		service.evaluationAnalytics.set(feature, analyticsEvent{
+			featureConfig: &rest.FeatureConfig{Feature: feature},
+			variation:     &rest.Variation{Identifier: "var1", Value: "value1"},
+			count:         1,
+		})
+	}
+	service.targetAnalytics.set("target1", evaluation.Target{Identifier: "target1"})
+	service.sendDataAndResetCache(context.Background(), 0)
+
+	t.Log("Then every exporter receives the same batches as the Feature Flag service, even if another exporter fails")
+	assert.Equal(t, 2, mClient.CallCount)
+	for _, e := range []*recordingExporter{failing, exporter} {
+		assert.Equal(t, []string{"test-env", "test-env"}, e.environments)
+		if assert.Len(t, e.metrics, 2) {
+			assert.Len(t, e.metrics[0], 1)
+			assert.Len(t, e.metrics[1], 1)
+			assert.Equal(t, *mClient.LastBody.MetricsData, e.metrics[1])
+		}
+		assert.Equal(t, "target1", e.targets[0][0].Identifier)
+	}
+}
+
+func TestExportersWithoutMetricsClient(t *testing.T) {
+	t.Run("When the service is started without a metrics client only the exporters receive the metrics", func(t *testing.T) {
+		spool := NewMemorySpool(10)
+		service := AnalyticsService{
+			evaluationAnalytics: newSafeEvaluationAnalytics(),
+			targetAnalytics:     newSafeTargetAnalytics(),
+			logger:              logger.NewNoOpLogger(),
+			environmentID:       "test-env",
+		}
+		service.SetSpool(spool, time.Hour)
+		exporter := &recordingExporter{}
+		service.AddExporter(exporter)
+
+		service.targetAnalytics.set("target1", evaluation.Target{Identifier: "target1"})
+		service.sendDataAndResetCache(context.Background(), 0)
+
+		assert.Equal(t, []string{"test-env"}, exporter.environments)
+		payloads, _ := spool.List()
+		assert.Empty(t, payloads)
+	})
+
+	t.Run("When the service was never started Flush sends the metrics to the exporters", func(t *testing.T) {
+		service := NewAnalyticsService(time.Hour, logger.NewNoOpLogger(), 10, time.Hour)
+		exporter := &recordingExporter{}
+		service.AddExporter(exporter)
+
+		for i := 0; i < 3; i++ {
+			service.PushToQueue(&rest.FeatureConfig{Feature: "feature1"}, &evaluation.Target{Identifier: "target1"}, &rest.Variation{Identifier: "var1", Value: "value1"})
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		assert.NoError(t, service.Flush(ctx))
+		if assert.Len(t, exporter.metrics, 1) && assert.Len(t, exporter.metrics[0], 1) {
+			assert.Equal(t, 3, exporter.metrics[0][0].Count)
+		}
+	})
+}
+
 func boolPtr(b bool) *bool {
 	return &b
 }
+
+func TestPushToQueueDoesNotBlock(t *testing.T) {
+	service := AnalyticsService{
+		analyticsChan:       make(chan analyticsEvent, 2),
+		evaluationAnalytics: newSafeEvaluationAnalytics(),
+		targetAnalytics:     newSafeTargetAnalytics(),
+		logger:              logger.NewNoOpLogger(),
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 5; i++ {
+			service.PushToQueue(&rest.FeatureConfig{Feature: "feature1"}, &evaluation.Target{Identifier: "target1"}, &rest.Variation{Identifier: "var1"})
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("PushToQueue blocked while the queue was full")
+	}
+	assert.Equal(t, uint64(3), service.DroppedEvents())
+	assert.Len(t, service.analyticsChan, 2)
+
+	// drops are reported once per interval
+	service.sendDataAndResetCache(context.Background(), 0)
+	assert.Equal(t, uint64(3), service.reportedDrops)
+}
+
+func TestFlush(t *testing.T) {
+	mClient := &MockMetricsClient{}
+	service := NewAnalyticsService(time.Hour, logger.NewNoOpLogger(), 10, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	service.Start(ctx, mClient, "test-env")
+
+	for i := 0; i < 3; i++ {
+		service.PushToQueue(&rest.FeatureConfig{Feature: "feature1"}, &evaluation.Target{Identifier: "target1"}, &rest.Variation{Identifier: "var1", Value: "value1"})
+	}
+
+	// the analytics goroutines are still running so Flush gives up when its deadline passes
+	shortCtx, shortCancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer shortCancel()
+	assert.ErrorIs(t, service.Flush(shortCtx), context.DeadlineExceeded)
+	assert.Equal(t, 0, mClient.CallCount)
+
+	cancel()
+	flushCtx, flushCancel := context.WithTimeout(context.Background(), time.Second)
+	defer flushCancel()
+	assert.NoError(t, service.Flush(flushCtx))
+
+	assert.Equal(t, 1, mClient.CallCount)
+	if assert.Len(t, *mClient.LastBody.MetricsData, 1) {
+		assert.Equal(t, 3, (*mClient.LastBody.MetricsData)[0].Count)
+	}
+	assert.Len(t, *mClient.LastBody.TargetData, 1)
+}
+
+func TestNewAnalyticsServiceWithOptions(t *testing.T) {
+	defaults := NewAnalyticsService(time.Minute, logger.NewNoOpLogger(), 10, time.Hour)
+	assert.Equal(t, maxAnalyticsEntries, defaults.maxEvaluationMetrics)
+	assert.Equal(t, maxTargetEntries, defaults.maxTargetMetrics)
+
+	service := NewAnalyticsServiceWithOptions(time.Minute, logger.NewNoOpLogger(), 10, time.Hour,
+		WithMaxEvaluationMetrics(50), WithMaxTargetMetrics(0))
+	assert.Equal(t, 50, service.maxEvaluationMetrics)
+	// a limit that isn't positive keeps the default
+	assert.Equal(t, maxTargetEntries, service.maxTargetMetrics)
+}
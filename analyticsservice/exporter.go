@@ -0,0 +1,89 @@
+package analyticsservice
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/harness/ff-golang-server-sdk/logger"
+	"github.com/harness/ff-golang-server-sdk/metricsclient"
+	"github.com/harness/ff-golang-server-sdk/sdk_codes"
+)
+
+// Exporter receives the metrics aggregated during each analytics interval, so they can be sent somewhere other
+// than the Feature Flag service. Large intervals are split into several batches, and Export is called once per
+// batch from a single goroutine. The slices are shared with the other exporters and must not be modified.
+type Exporter interface {
+	Export(ctx context.Context, environmentID string, metrics []metricsclient.MetricsData, targets []metricsclient.TargetData) error
+}
+
+// harnessExporter sends metrics to the Feature Flag service
+type harnessExporter struct {
+	client metricsclient.ClientWithResponsesInterface
+	logger logger.Logger
+}
+
+var _ Exporter = &harnessExporter{}
+
+// Export posts a batch of metrics to the Feature Flag service
+func (e *harnessExporter) Export(ctx context.Context, environmentID string, metrics []metricsclient.MetricsData, targets []metricsclient.TargetData) error {
+	analyticsPayload := metricsclient.PostMetricsJSONRequestBody{
+		MetricsData: &metrics,
+		TargetData:  &targets,
+	}
+	jsonData, err := json.Marshal(analyticsPayload)
+	if err != nil {
+		e.logger.Errorf(err.Error())
+	}
+	e.logger.Debug(string(jsonData))
+
+	resp, err := e.client.PostMetricsWithResponse(ctx, metricsclient.EnvironmentPathParam(environmentID), nil, analyticsPayload)
+	if err != nil {
+		return err
+	}
+	if resp == nil {
+		return errors.New("empty response from metrics server")
+	}
+	if resp.StatusCode() != 200 {
+		return fmt.Errorf("%s Non 200 response from metrics server: %d", sdk_codes.MetricsSendFail, resp.StatusCode())
+	}
+	e.logger.Debugf("%s Metrics sent to server", sdk_codes.MetricsSendSuccess)
+	return nil
+}
+
+// JSONExporter writes each batch of metrics to a writer as a line of JSON, e.g. to os.Stdout or a file that's
+// collected into a data warehouse
+type JSONExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+var _ Exporter = &JSONExporter{}
+
+// jsonBatch is the line written by JSONExporter
+type jsonBatch struct {
+	Environment string                      `json:"environment"`
+	MetricsData []metricsclient.MetricsData `json:"metricsData"`
+	TargetData  []metricsclient.TargetData  `json:"targetData"`
+}
+
+// NewJSONExporter creates an exporter that writes to w
+func NewJSONExporter(w io.Writer) *JSONExporter {
+	return &JSONExporter{w: w}
+}
+
+// Export writes the batch as a single line of JSON
+func (e *JSONExporter) Export(ctx context.Context, environmentID string, metrics []metricsclient.MetricsData, targets []metricsclient.TargetData) error {
+	line, err := json.Marshal(jsonBatch{Environment: environmentID, MetricsData: metrics, TargetData: targets})
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, err = e.w.Write(append(line, '\n'))
+	return err
+}
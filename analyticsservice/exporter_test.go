@@ -0,0 +1,30 @@
+package analyticsservice
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/harness/ff-golang-server-sdk/metricsclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONExporter(t *testing.T) {
+	var buf bytes.Buffer
+	exporter := NewJSONExporter(&buf)
+
+	metrics := []metricsclient.MetricsData{{Count: 3, MetricsType: metricsclient.MetricsDataMetricsType(ffMetricType), Timestamp: 1715600410545, Attributes: []metricsclient.KeyValue{{Key: featureIdentifierAttribute, Value: "feature1"}}}}
+	targets := []metricsclient.TargetData{{Identifier: "target1", Name: "Target One", Attributes: []metricsclient.KeyValue{}}}
+	assert.Nil(t, exporter.Export(context.Background(), "test-env", metrics, targets))
+	assert.Nil(t, exporter.Export(context.Background(), "test-env", nil, targets))
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if assert.Len(t, lines, 2) {
+		assert.JSONEq(t, `{
+			"environment": "test-env",
+			"metricsData": [{"count": 3, "metricsType": "FFMETRICS", "timestamp": 1715600410545, "attributes": [{"key": "featureIdentifier", "value": "feature1"}]}],
+			"targetData": [{"identifier": "target1", "name": "Target One", "attributes": []}]
+		}`, lines[0])
+	}
+}
@@ -0,0 +1,237 @@
+package analyticsservice
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/harness/ff-golang-server-sdk/metricsclient"
+)
+
+const (
+	// defaultSpoolMaxAge is how long failed metrics are retried for if no max age is given
+	defaultSpoolMaxAge = 24 * time.Hour
+	// maxSpoolBackoff is the longest time between retries of a payload
+	maxSpoolBackoff = time.Hour
+	// spoolFilePrefix and spoolFileSuffix name the files written by DiskSpool
+	spoolFilePrefix = "metrics-"
+	spoolFileSuffix = ".json"
+)
+
+// SpooledPayload is a metrics payload that couldn't be sent to the Feature Flag service
+type SpooledPayload struct {
+	Environment string                                   `json:"environment"`
+	Payload     metricsclient.PostMetricsJSONRequestBody `json:"payload"`
+	// Created is when the payload was first sent, it's dropped once it's older than the spool's max age
+	Created     time.Time `json:"created"`
+	Attempts    int       `json:"attempts"`
+	NextAttempt time.Time `json:"nextAttempt"`
+	// ID identifies the payload in the spool it was listed from, it's set by List
+	ID string `json:"-"`
+}
+
+// Spool holds the metrics payloads that failed to send so they can be retried. Payloads are listed without
+// being removed, and are only removed once they've been sent, dropped or added again, so a payload isn't
+// lost if the process exits while it's being retried.
+type Spool interface {
+	// Add stores a payload, if the spool is full the oldest payload is dropped
+	Add(payload SpooledPayload) error
+	// List returns every payload in the spool, oldest first
+	List() ([]SpooledPayload, error)
+	// Remove removes a payload returned by List, it's not an error if the payload was already removed
+	Remove(payload SpooledPayload) error
+}
+
+// MemorySpool is a Spool that holds payloads in memory, they're lost when the process exits
+type MemorySpool struct {
+	mu          sync.Mutex
+	maxPayloads int
+	payloads    []SpooledPayload
+	seq         uint64
+}
+
+var _ Spool = &MemorySpool{}
+
+// NewMemorySpool creates a spool that holds up to maxPayloads payloads in memory
+func NewMemorySpool(maxPayloads int) *MemorySpool {
+	return &MemorySpool{maxPayloads: maxPayloads}
+}
+
+// Add stores a payload, if the spool is full the oldest payload is dropped
+func (s *MemorySpool) Add(payload SpooledPayload) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	payload.ID = fmt.Sprint(s.seq)
+	s.payloads = append(s.payloads, payload)
+	sort.SliceStable(s.payloads, func(i, j int) bool {
+		return s.payloads[i].Created.Before(s.payloads[j].Created)
+	})
+	if s.maxPayloads > 0 && len(s.payloads) > s.maxPayloads {
+		s.payloads = s.payloads[len(s.payloads)-s.maxPayloads:]
+	}
+	return nil
+}
+
+// List returns every payload in the spool, oldest first
+func (s *MemorySpool) List() ([]SpooledPayload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SpooledPayload(nil), s.payloads...), nil
+}
+
+// Remove removes a payload returned by List
+func (s *MemorySpool) Remove(payload SpooledPayload) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.payloads {
+		if s.payloads[i].ID == payload.ID {
+			s.payloads = append(s.payloads[:i], s.payloads[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// DiskSpool is a Spool that writes each payload to a file in a directory, so metrics that couldn't be sent
+// before the process exited are retried when it starts again
+type DiskSpool struct {
+	mu          sync.Mutex
+	dir         string
+	maxPayloads int
+	seq         uint64
+}
+
+var _ Spool = &DiskSpool{}
+
+// NewDiskSpool creates a spool that holds up to maxPayloads payloads in dir, creating dir if it doesn't exist
+func NewDiskSpool(dir string, maxPayloads int) (*DiskSpool, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &DiskSpool{dir: dir, maxPayloads: maxPayloads}, nil
+}
+
+// Add writes a payload to the spool directory, if the spool is full the oldest payload is removed
+func (s *DiskSpool) Add(payload SpooledPayload) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	content, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	// files are named by creation time so they sort oldest first, the sequence number keeps the names unique
+	s.seq++
+	name := fmt.Sprintf("%s%020d-%06d%s", spoolFilePrefix, payload.Created.UnixNano(), s.seq, spoolFileSuffix)
+	file, err := os.CreateTemp(s.dir, name+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := file.Name()
+	defer func() {
+		_ = os.Remove(tmpPath)
+	}()
+	if _, err := file.Write(content); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, filepath.Join(s.dir, name)); err != nil {
+		return err
+	}
+
+	if s.maxPayloads <= 0 {
+		return nil
+	}
+	names, err := s.files()
+	if err != nil {
+		return err
+	}
+	for len(names) > s.maxPayloads {
+		if err := os.Remove(filepath.Join(s.dir, names[0])); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		names = names[1:]
+	}
+	return nil
+}
+
+// List reads every payload in the spool directory, oldest first. Files that can't be decoded are removed
+// and reported in the returned error along with the payloads that could be read.
+func (s *DiskSpool) List() ([]SpooledPayload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names, err := s.files()
+	if err != nil {
+		return nil, err
+	}
+
+	var payloads []SpooledPayload
+	var errs []error
+	for _, name := range names {
+		path := filepath.Join(s.dir, name)
+		content, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		var payload SpooledPayload
+		if err := json.Unmarshal(content, &payload); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			if err := os.Remove(path); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		// a payload written without its attempts still failed to send once
+		if payload.Attempts < 1 {
+			payload.Attempts = 1
+		}
+		payload.ID = name
+		payloads = append(payloads, payload)
+	}
+	return payloads, errors.Join(errs...)
+}
+
+// Remove removes the file of a payload returned by List
+func (s *DiskSpool) Remove(payload SpooledPayload) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// the ID is a file name from List, anything else would name a file outside the spool
+	if payload.ID == "" || filepath.Base(payload.ID) != payload.ID {
+		return fmt.Errorf("invalid spooled payload id %q", payload.ID)
+	}
+	if err := os.Remove(filepath.Join(s.dir, payload.ID)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// files returns the names of the payload files in the spool directory, oldest first
+func (s *DiskSpool) files() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, spoolFilePrefix) || !strings.HasSuffix(name, spoolFileSuffix) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,139 @@
+package analyticsservice
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/harness/ff-golang-server-sdk/metricsclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func spooledPayload(identifier string, created time.Time) SpooledPayload {
+	return SpooledPayload{
+		Environment: "test-env",
+		Payload: metricsclient.PostMetricsJSONRequestBody{
+			TargetData: &[]metricsclient.TargetData{{Identifier: identifier, Attributes: []metricsclient.KeyValue{}}},
+		},
+		Created:  created,
+		Attempts: 1,
+	}
+}
+
+func spooledIdentifiers(payloads []SpooledPayload) []string {
+	identifiers := make([]string, 0, len(payloads))
+	for _, payload := range payloads {
+		identifiers = append(identifiers, (*payload.Payload.TargetData)[0].Identifier)
+	}
+	return identifiers
+}
+
+// takeSpooled lists and removes every payload in spool
+func takeSpooled(t *testing.T, spool Spool) []SpooledPayload {
+	payloads, err := spool.List()
+	assert.Nil(t, err)
+	for _, payload := range payloads {
+		assert.Nil(t, spool.Remove(payload))
+	}
+	return payloads
+}
+
+func TestSpools(t *testing.T) {
+	diskSpool, err := NewDiskSpool(filepath.Join(t.TempDir(), "spool"), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spools := map[string]Spool{
+		"MemorySpool": NewMemorySpool(2),
+		"DiskSpool":   diskSpool,
+	}
+
+	for name, spool := range spools {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+			assert.Nil(t, spool.Add(spooledPayload("second", now.Add(-time.Minute))))
+			assert.Nil(t, spool.Add(spooledPayload("first", now.Add(-2*time.Minute))))
+
+			t.Log("When the spool is full the oldest payload is dropped")
+			assert.Nil(t, spool.Add(spooledPayload("third", now)))
+
+			payloads, err := spool.List()
+			assert.Nil(t, err)
+			assert.Equal(t, []string{"second", "third"}, spooledIdentifiers(payloads))
+			assert.Equal(t, "test-env", payloads[0].Environment)
+			assert.True(t, payloads[0].Created.Equal(now.Add(-time.Minute)))
+
+			t.Log("And List doesn't remove the payloads")
+			listed, err := spool.List()
+			assert.Nil(t, err)
+			assert.Equal(t, payloads, listed)
+
+			t.Log("And Remove only removes the payload it's given")
+			assert.Nil(t, spool.Remove(payloads[0]))
+			assert.Nil(t, spool.Remove(payloads[0]))
+			listed, err = spool.List()
+			assert.Nil(t, err)
+			assert.Equal(t, []string{"third"}, spooledIdentifiers(listed))
+
+			assert.Nil(t, spool.Remove(listed[0]))
+			listed, err = spool.List()
+			assert.Nil(t, err)
+			assert.Empty(t, listed)
+		})
+	}
+}
+
+func TestDiskSpool_SurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	spool, err := NewDiskSpool(dir, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, spool.Add(spooledPayload("target1", time.Now())))
+
+	// a corrupt file is removed and reported without losing the other payloads
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, spoolFilePrefix+"0"+spoolFileSuffix), []byte("{"), 0600))
+
+	restarted, err := NewDiskSpool(dir, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payloads, err := restarted.List()
+	assert.NotNil(t, err)
+	assert.Equal(t, []string{"target1"}, spooledIdentifiers(payloads))
+
+	entries, err := os.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+
+	t.Log("And a payload stays on disk until it's removed")
+	restarted, err = NewDiskSpool(dir, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"target1"}, spooledIdentifiers(takeSpooled(t, restarted)))
+	entries, err = os.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Empty(t, entries)
+
+	assert.NotNil(t, restarted.Remove(SpooledPayload{ID: "../" + filepath.Base(dir)}))
+}
+
+func TestDiskSpool_ListWithoutAttempts(t *testing.T) {
+	dir := t.TempDir()
+	spool, err := NewDiskSpool(dir, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := `{"environment":"test-env","payload":{},"created":"2024-01-01T00:00:00Z"}`
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, spoolFilePrefix+"0"+spoolFileSuffix), []byte(content), 0600))
+
+	t.Log("When a spooled file doesn't record its attempts it's listed as having been attempted once")
+	payloads, err := spool.List()
+	assert.Nil(t, err)
+	if assert.Len(t, payloads, 1) {
+		assert.Equal(t, 1, payloads[0].Attempts)
+	}
+}
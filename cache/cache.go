@@ -6,8 +6,12 @@ import (
 	"time"
 )
 
-// Cache wrapper to integrate any 3rd party implementation
+// Cache holds the flags and segments used for evaluations, and can wrap any 3rd party implementation.
+// The repository only keeps flags and segments in the cache, so implementations must not silently drop
+// entries: Set returns true if an entry had to be evicted to make room, which is logged as an error.
+// Updated returns when the content last changed. Implementations must be safe for concurrent use.
 type Cache interface {
+	// Set stores value under key, returning true if another entry was evicted
 	Set(key, value interface{}) (evicted bool)
 	Contains(key interface{}) bool
 	Get(key interface{}) (value interface{}, ok bool)
@@ -15,6 +19,7 @@ type Cache interface {
 	Len() int
 	Purge()
 	Remove(key interface{}) (present bool)
+	// Resize changes the number of entries the cache can hold, returning the number evicted
 	Resize(size int) (evicted int)
 	Updated() time.Time
 	SetLogger(logger logger.Logger)
@@ -5,12 +5,16 @@ import (
 	lru "github.com/hashicorp/golang-lru"
 
 	"reflect"
+	"sync"
 	"time"
 )
 
-// LRUCache is thread-safe LAST READ USED Cache
+// LRUCache is thread-safe LAST READ USED Cache. When it's full the least recently used entry is evicted
+// to make room and Set returns true, so it should be sized to hold every flag and segment.
 type LRUCache struct {
 	*lru.Cache
+	mu         sync.Mutex
+	capacity   int
 	logger     logger.Logger
 	lastUpdate time.Time
 }
@@ -24,8 +28,9 @@ func NewLruCache(size int, logger logger.Logger) (*LRUCache, error) {
 	}
 	logger.Infof("Cache successfully initialized with size: %d", size)
 	return &LRUCache{
-		Cache:  cache,
-		logger: logger,
+		Cache:    cache,
+		capacity: size,
+		logger:   logger,
 	}, nil
 }
 
@@ -36,14 +41,16 @@ func (lru *LRUCache) getTime() time.Time {
 // Set a new value if it is different from the previous one.
 // Returns true if an eviction occurred.
 func (lru *LRUCache) Set(key interface{}, value interface{}) (evicted bool) {
-	prev, _ := lru.Get(key)
-	if !reflect.DeepEqual(prev, value) {
-		add := lru.Cache.Add(key, value)
-		lru.lastUpdate = lru.getTime()
-		lru.logger.Debugf("cache value changed for key %s with value %v", key, value)
-		return add
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+	prev, ok := lru.Cache.Peek(key)
+	if ok && reflect.DeepEqual(prev, value) {
+		return false
 	}
-	return false
+	add := lru.Cache.Add(key, value)
+	lru.lastUpdate = lru.getTime()
+	lru.logger.Debugf("cache value changed for key %s with value %v", key, value)
+	return add
 }
 
 // Contains checks if a key is in the cache
@@ -68,12 +75,16 @@ func (lru *LRUCache) Len() int {
 
 // Purge is used to completely clear the cache.
 func (lru *LRUCache) Purge() {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
 	lru.Cache.Purge()
 	lru.lastUpdate = lru.getTime()
 }
 
 // Remove removes the provided key from the cache.
 func (lru *LRUCache) Remove(key interface{}) (present bool) {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
 	present = lru.Cache.Remove(key)
 	lru.lastUpdate = lru.getTime()
 	if present {
@@ -84,11 +95,27 @@ func (lru *LRUCache) Remove(key interface{}) (present bool) {
 
 // Resize changes the cache size.
 func (lru *LRUCache) Resize(size int) (evicted int) {
-	return lru.Cache.Resize(size)
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+	lru.capacity = size
+	evicted = lru.Cache.Resize(size)
+	if evicted > 0 {
+		lru.lastUpdate = lru.getTime()
+	}
+	return evicted
+}
+
+// Cap returns the number of entries the cache can hold before it evicts
+func (lru *LRUCache) Cap() int {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+	return lru.capacity
 }
 
 // Updated lastUpdate information
 func (lru *LRUCache) Updated() time.Time {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
 	return lru.lastUpdate
 }
 
@@ -1,97 +1,285 @@
 package cache
 
 import (
+	"crypto/sha256"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+
 	"github.com/harness/ff-golang-server-sdk/dto"
-	"github.com/harness/ff-golang-server-sdk/evaluation"
 	"github.com/harness/ff-golang-server-sdk/logger"
+	"github.com/harness/ff-golang-server-sdk/rest"
 	"github.com/harness/ff-golang-server-sdk/storage"
-	"github.com/mitchellh/mapstructure"
 )
 
-// Persistence persist cache data to a storage
+const (
+	// storeKeyEnvironment holds the environment the persisted flags were retrieved from
+	storeKeyEnvironment = "environment"
+	// storeKeySDKKey holds a hash of the SDK key the persisted flags were retrieved with
+	storeKeySDKKey = "sdk-key"
+	// storeKeyFlags holds the identifiers of the persisted flags, each flag is stored under its cache key
+	storeKeyFlags = "flag-identifiers"
+	// storeKeySegments holds the identifiers of the persisted segments, each segment is stored under its cache key
+	storeKeySegments = "segment-identifiers"
+)
+
+// flagKey is the key a flag is held under, in the cache by the repository and in the store
+func flagKey(identifier string) string {
+	return dto.KeyFeature + "/" + identifier
+}
+
+// segmentKey is the key a segment is held under, in the cache by the repository and in the store
+func segmentKey(identifier string) string {
+	return dto.KeySegment + "/" + identifier
+}
+
+// Snapshot is the flags and segments of an environment that are persisted to a store
+type Snapshot struct {
+	Environment string               `json:"-"`
+	Flags       []rest.FeatureConfig `json:"flags"`
+	Segments    []rest.Segment       `json:"segments"`
+}
+
+// PersistenceOption configures a Persistence
+type PersistenceOption func(p *Persistence)
+
+// WithSDKKey ties the persisted flags to an SDK key, a store written with a different key isn't loaded. Only a
+// hash of the key is written to the store.
+func WithSDKKey(sdkKey string) PersistenceOption {
+	return func(p *Persistence) {
+		p.sdkKey = HashSDKKey(sdkKey)
+	}
+}
+
+// HashSDKKey identifies an SDK key without revealing it, e.g. to name the file its flags are persisted to
+func HashSDKKey(sdkKey string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(sdkKey)))
+}
+
+// Persistence persist cache data to a storage. Each flag and segment is stored under its own key and only
+// the keys that changed since the last save are set, so stores such as storage.BoltStore only write what
+// changed.
 type Persistence struct {
 	store  storage.Storage
 	cache  Cache
 	logger logger.Logger
+	sdkKey string
+	// written holds a fingerprint of each key in the store
+	written map[string]uint64
 }
 
 // NewPersistence creates a new instance for persisting data
-func NewPersistence(store storage.Storage, cache Cache, logger logger.Logger) Persistence {
-	return Persistence{store: store, cache: cache, logger: logger}
+func NewPersistence(store storage.Storage, cache Cache, logger logger.Logger, options ...PersistenceOption) Persistence {
+	p := Persistence{store: store, cache: cache, logger: logger, written: map[string]uint64{}}
+	for _, opt := range options {
+		opt(&p)
+	}
+	return p
 }
 
-// SaveToStore saves data to declared storage only if last update
+// SaveToStore saves the flags and segments held in the cache to declared storage only if last update
 // is greater than persisted time
 func (p Persistence) SaveToStore() error {
 	if p.cache.Updated().Before(p.store.PersistedAt()) {
 		return nil
 	}
 	p.logger.Info("Persisting cache data to the store")
-	keys := p.cache.Keys()
-	temp := make(map[string]interface{})
-	for _, key := range keys {
-		keyObject := key.(dto.Key)
-		val, ok := p.cache.Get(key)
-		if ok {
-
-			if _, ok := temp[keyObject.Type]; !ok {
-				temp[keyObject.Type] = make(map[string]interface{})
+
+	var snapshot Snapshot
+	flagsPrefix, segmentsPrefix := dto.KeyFeatures+"/", dto.KeySegments+"/"
+	for _, key := range p.cache.Keys() {
+		k, ok := key.(string)
+		if !ok {
+			continue
+		}
+		value, ok := p.cache.Get(key)
+		if !ok {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(k, flagKey("")):
+			if flag, ok := value.(rest.FeatureConfig); ok {
+				snapshot.Flags = append(snapshot.Flags, flag)
+			}
+		case strings.HasPrefix(k, segmentKey("")):
+			if segment, ok := value.(rest.Segment); ok {
+				snapshot.Segments = append(snapshot.Segments, segment)
 			}
-			nameValue := temp[keyObject.Type].(map[string]interface{})
-			nameValue[keyObject.Name] = val
+		case strings.HasPrefix(k, flagsPrefix):
+			snapshot.Environment = strings.TrimPrefix(k, flagsPrefix)
+		case strings.HasPrefix(k, segmentsPrefix):
+			snapshot.Environment = strings.TrimPrefix(k, segmentsPrefix)
 		}
 	}
+	sort.Slice(snapshot.Flags, func(i, j int) bool { return snapshot.Flags[i].Feature < snapshot.Flags[j].Feature })
+	sort.Slice(snapshot.Segments, func(i, j int) bool {
+		return snapshot.Segments[i].Identifier < snapshot.Segments[j].Identifier
+	})
+	return p.Save(snapshot)
+}
 
-	for key, val := range temp {
-		if err := p.store.Set(key, val); err != nil {
-			p.logger.Debugf("error while storing data, err: %v", err)
-		}
-	}
-	err := p.store.Persist()
+// LoadFromStore loads all stored data into specified cache, under the keys the repository reads them from
+func (p *Persistence) LoadFromStore() error {
+	p.logger.Info("Loading cache data from store")
+	snapshot, err := p.Load()
 	if err != nil {
 		return err
 	}
+
+	for _, flag := range snapshot.Flags {
+		p.cache.Set(flagKey(flag.Feature), flag)
+	}
+	for _, segment := range snapshot.Segments {
+		p.cache.Set(segmentKey(segment.Identifier), segment)
+	}
+	p.cache.Set(dto.KeyFeatures+"/"+snapshot.Environment, snapshot.Flags)
+	p.cache.Set(dto.KeySegments+"/"+snapshot.Environment, snapshot.Segments)
 	return nil
 }
 
-// LoadFromStore loads all stored data into specified cache
-func (p *Persistence) LoadFromStore() error {
-	p.logger.Info("Loading cache data from store")
-	err := p.store.Load()
-	if err != nil {
-		return err
+// Save writes the snapshot to the store. Only the keys whose value changed since the last Save or Load are
+// set, and the keys of flags and segments that are no longer in the snapshot are removed.
+func (p *Persistence) Save(snapshot Snapshot) error {
+	flagIdentifiers := make([]string, 0, len(snapshot.Flags))
+	segmentIdentifiers := make([]string, 0, len(snapshot.Segments))
+	values := map[string]interface{}{
+		storeKeyEnvironment: snapshot.Environment,
+		storeKeySDKKey:      p.sdkKey,
+	}
+	for _, flag := range snapshot.Flags {
+		flagIdentifiers = append(flagIdentifiers, flag.Feature)
+		values[flagKey(flag.Feature)] = flag
 	}
+	for _, segment := range snapshot.Segments {
+		segmentIdentifiers = append(segmentIdentifiers, segment.Identifier)
+		values[segmentKey(segment.Identifier)] = segment
+	}
+	values[storeKeyFlags] = flagIdentifiers
+	values[storeKeySegments] = segmentIdentifiers
 
-	flags, ok := p.store.Get(dto.KeyFeature)
-	if ok {
-		for key, value := range flags.(map[string]interface{}) {
-			keyData := dto.Key{
-				Type: dto.KeyFeature,
-				Name: key,
-			}
-			flag := evaluation.FeatureConfig{}
-			err := mapstructure.Decode(value, &flag)
-			if err != nil {
+	written := make(map[string]uint64, len(values))
+	for key, value := range values {
+		sum := fingerprint(value)
+		written[key] = sum
+		if old, ok := p.written[key]; ok && old == sum {
+			continue
+		}
+		if err := p.store.Set(key, value); err != nil {
+			return err
+		}
+	}
+	for key := range p.written {
+		if _, ok := values[key]; !ok {
+			if err := p.store.Remove(key); err != nil {
 				return err
 			}
-			p.cache.Set(keyData, flag)
 		}
 	}
-
-	segments, ok := p.store.Get(dto.KeySegment)
-	if ok {
-		for key, value := range segments.(map[string]interface{}) {
-			keyData := dto.Key{
-				Type: dto.KeySegment,
-				Name: key,
-			}
-			segment := evaluation.Segment{}
-			err := mapstructure.Decode(value, &segment)
-			if err != nil {
+	// the flags and segments were stored as a single list by earlier versions
+	for _, key := range []string{dto.KeyFeatures, dto.KeySegments} {
+		if _, ok := p.store.Get(key); ok {
+			if err := p.store.Remove(key); err != nil {
 				return err
 			}
-			p.cache.Set(keyData, value)
 		}
 	}
+
+	if err := p.store.Persist(); err != nil {
+		return err
+	}
+	p.written = written
 	return nil
 }
+
+// Load reads the snapshot from the store. The store holds decoded JSON rather than the rest types, so the
+// values are converted by encoding them again.
+func (p *Persistence) Load() (Snapshot, error) {
+	if err := p.store.Load(); err != nil {
+		return Snapshot{}, err
+	}
+	if p.sdkKey != "" {
+		if key, _ := p.store.Get(storeKeySDKKey); key != p.sdkKey {
+			return Snapshot{}, fmt.Errorf("the store holds flags for a different SDK key")
+		}
+	}
+
+	var flags, segments interface{}
+	flagIdentifiers, ok := p.storedIdentifiers(storeKeyFlags)
+	segmentIdentifiers, _ := p.storedIdentifiers(storeKeySegments)
+	if ok {
+		flags = p.storedValues(flagIdentifiers, flagKey)
+		segments = p.storedValues(segmentIdentifiers, segmentKey)
+	} else {
+		// the flags and segments were stored as a single list by earlier versions
+		flags, _ = p.store.Get(dto.KeyFeatures)
+		segments, _ = p.store.Get(dto.KeySegments)
+	}
+	content, err := jsoniter.Marshal(map[string]interface{}{"flags": flags, "segments": segments})
+	if err != nil {
+		return Snapshot{}, err
+	}
+	var snapshot Snapshot
+	if err := jsoniter.Unmarshal(content, &snapshot); err != nil {
+		return Snapshot{}, err
+	}
+	envID, _ := p.store.Get(storeKeyEnvironment)
+	snapshot.Environment, _ = envID.(string)
+
+	// record what's in the store so the next save only writes what changed, keys written by earlier
+	// versions aren't recorded so they're rewritten
+	if ok {
+		p.written[storeKeyEnvironment] = fingerprint(snapshot.Environment)
+		p.written[storeKeySDKKey] = fingerprint(p.sdkKey)
+		p.written[storeKeyFlags] = fingerprint(flagIdentifiers)
+		p.written[storeKeySegments] = fingerprint(segmentIdentifiers)
+		for _, flag := range snapshot.Flags {
+			p.written[flagKey(flag.Feature)] = fingerprint(flag)
+		}
+		for _, segment := range snapshot.Segments {
+			p.written[segmentKey(segment.Identifier)] = fingerprint(segment)
+		}
+	}
+	return snapshot, nil
+}
+
+// storedIdentifiers returns the list of identifiers held under key
+func (p *Persistence) storedIdentifiers(key string) ([]string, bool) {
+	value, ok := p.store.Get(key)
+	if !ok {
+		return nil, false
+	}
+	content, err := jsoniter.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+	var identifiers []string
+	if err := jsoniter.Unmarshal(content, &identifiers); err != nil {
+		return nil, false
+	}
+	return identifiers, true
+}
+
+// storedValues returns the values stored for the identifiers, skipping any that are missing
+func (p *Persistence) storedValues(identifiers []string, key func(string) string) []interface{} {
+	values := make([]interface{}, 0, len(identifiers))
+	for _, identifier := range identifiers {
+		if value, ok := p.store.Get(key(identifier)); ok {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// fingerprint hashes the JSON encoding of a value
+func fingerprint(v interface{}) uint64 {
+	b, err := jsoniter.Marshal(v)
+	if err != nil {
+		return 0
+	}
+	h := fnv.New64a()
+	_, _ = h.Write(b)
+	return h.Sum64()
+}
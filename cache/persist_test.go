@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/harness/ff-golang-server-sdk/dto"
+	"github.com/harness/ff-golang-server-sdk/logger"
+	"github.com/harness/ff-golang-server-sdk/rest"
+	"github.com/harness/ff-golang-server-sdk/storage"
+	"github.com/harness/ff-golang-server-sdk/test_helpers"
+	"github.com/stretchr/testify/assert"
+)
+
+const testSDKKey = "27bed8d2-2610-462b-90eb-d80fd594b623"
+
+// recordingStore is a store that records the keys it's asked to set and remove
+type recordingStore struct {
+	storage.Storage
+	sets    []string
+	removes []string
+}
+
+func (s *recordingStore) Set(key string, value interface{}) error {
+	s.sets = append(s.sets, key)
+	return s.Storage.Set(key, value)
+}
+
+func (s *recordingStore) Remove(key string) error {
+	s.removes = append(s.removes, key)
+	return s.Storage.Remove(key)
+}
+
+func (s *recordingStore) reset() {
+	s.sets = nil
+	s.removes = nil
+}
+
+func newTestPersistence(store storage.Storage) Persistence {
+	return NewPersistence(store, nil, logger.NewNoOpLogger(), WithSDKKey(testSDKKey))
+}
+
+func TestPersistence_Save(t *testing.T) {
+	dir := t.TempDir()
+	store := &recordingStore{Storage: storage.NewFileStore("test", dir, logger.NewNoOpLogger())}
+	persistence := newTestPersistence(store)
+
+	darkMode := test_helpers.MakeBoolFeatureConfig("darkMode", "true", "false", "on", nil)
+	beta := test_helpers.MakeBoolFeatureConfig("beta", "true", "false", "on", nil)
+	assert.Nil(t, persistence.Save(Snapshot{Environment: "env", Flags: []rest.FeatureConfig{darkMode, beta}}))
+	assert.ElementsMatch(t, []string{storeKeyEnvironment, storeKeySDKKey, storeKeyFlags, storeKeySegments,
+		flagKey("darkMode"), flagKey("beta")}, store.sets)
+
+	t.Run("Only the flags that changed are set", func(t *testing.T) {
+		store.reset()
+		darkMode.State = rest.FeatureStateOff
+		assert.Nil(t, persistence.Save(Snapshot{Environment: "env", Flags: []rest.FeatureConfig{darkMode, beta}}))
+		assert.Equal(t, []string{flagKey("darkMode")}, store.sets)
+		assert.Empty(t, store.removes)
+
+		store.reset()
+		assert.Nil(t, persistence.Save(Snapshot{Environment: "env", Flags: []rest.FeatureConfig{darkMode}}))
+		assert.Equal(t, []string{storeKeyFlags}, store.sets)
+		assert.Equal(t, []string{flagKey("beta")}, store.removes)
+	})
+
+	t.Run("Nothing is set after loading unchanged flags", func(t *testing.T) {
+		reloaded := &recordingStore{Storage: storage.NewFileStore("test", dir, logger.NewNoOpLogger())}
+		restarted := newTestPersistence(reloaded)
+		snapshot, err := restarted.Load()
+		assert.Nil(t, err)
+		assert.Equal(t, "env", snapshot.Environment)
+		if assert.Len(t, snapshot.Flags, 1) {
+			assert.Equal(t, rest.FeatureStateOff, snapshot.Flags[0].State)
+		}
+
+		assert.Nil(t, restarted.Save(snapshot))
+		assert.Empty(t, reloaded.sets)
+		assert.Empty(t, reloaded.removes)
+	})
+
+	t.Run("Flags stored for another SDK key aren't loaded", func(t *testing.T) {
+		other := NewPersistence(storage.NewFileStore("test", dir, logger.NewNoOpLogger()), nil, logger.NewNoOpLogger(), WithSDKKey("other"))
+		_, err := other.Load()
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Flags stored as a single list by earlier versions are loaded and rewritten", func(t *testing.T) {
+		legacyDir := t.TempDir()
+		legacy := storage.NewFileStore("test", legacyDir, logger.NewNoOpLogger())
+		assert.Nil(t, legacy.Reset(map[string]interface{}{
+			dto.KeyFeatures:     []rest.FeatureConfig{darkMode},
+			dto.KeySegments:     []rest.Segment{},
+			storeKeyEnvironment: "env",
+			storeKeySDKKey:      HashSDKKey(testSDKKey),
+		}, true))
+
+		upgraded := &recordingStore{Storage: storage.NewFileStore("test", legacyDir, logger.NewNoOpLogger())}
+		restarted := newTestPersistence(upgraded)
+		snapshot, err := restarted.Load()
+		assert.Nil(t, err)
+		assert.Len(t, snapshot.Flags, 1)
+
+		assert.Nil(t, restarted.Save(snapshot))
+		assert.Contains(t, upgraded.sets, flagKey("darkMode"))
+		assert.ElementsMatch(t, []string{dto.KeyFeatures, dto.KeySegments}, upgraded.removes)
+	})
+}
+
+func TestPersistence_SaveToStoreAndLoadFromStore(t *testing.T) {
+	dir := t.TempDir()
+	lru, err := NewLruCache(100, logger.NewNoOpLogger())
+	assert.Nil(t, err)
+	darkMode := test_helpers.MakeBoolFeatureConfig("darkMode", "true", "false", "on", nil)
+	segment := rest.Segment{Identifier: "beta", Name: "beta"}
+	lru.Set(flagKey("darkMode"), darkMode)
+	lru.Set(segmentKey("beta"), segment)
+	lru.Set(dto.KeyFeatures+"/env", []rest.FeatureConfig{darkMode})
+
+	persistence := NewPersistence(storage.NewFileStore("test", dir, logger.NewNoOpLogger()), lru, logger.NewNoOpLogger())
+	assert.Nil(t, persistence.SaveToStore())
+
+	loaded, err := NewLruCache(100, logger.NewNoOpLogger())
+	assert.Nil(t, err)
+	restarted := NewPersistence(storage.NewFileStore("test", dir, logger.NewNoOpLogger()), loaded, logger.NewNoOpLogger())
+	assert.Nil(t, restarted.LoadFromStore())
+
+	flag, ok := loaded.Get(flagKey("darkMode"))
+	if assert.True(t, ok) {
+		assert.Equal(t, darkMode.Feature, flag.(rest.FeatureConfig).Feature)
+		assert.Equal(t, darkMode.State, flag.(rest.FeatureConfig).State)
+	}
+	stored, ok := loaded.Get(segmentKey("beta"))
+	assert.True(t, ok)
+	assert.Equal(t, segment, stored)
+	flags, ok := loaded.Get(dto.KeyFeatures + "/env")
+	if assert.True(t, ok) {
+		assert.Len(t, flags, 1)
+	}
+}
@@ -63,12 +63,24 @@ type CfClient struct {
 	initializedBoolLock     sync.RWMutex
 	initializedChan         chan struct{}
 	initializedErrChan      chan error
+	retrievedChan           chan struct{}
+	retrievedOnce           sync.Once
 	analyticsService        *analyticsservice.AnalyticsService
 	clusterIdentifier       string
 	stop                    chan struct{}
 	stopped                 *atomicBool
+	changeNotifier          *flagChangeNotifier
+	persister               *storePersister
+	// wg tracks the goroutines started by start, CloseWithContext waits for them to exit
+	wg sync.WaitGroup
 }
 
+// defaultCloseTimeout is how long Close waits for goroutines to exit and analytics to be sent
+const defaultCloseTimeout = 5 * time.Second
+
+// clientNotReadyReason is returned by the detail variation methods when the client hasn't initialized yet
+var clientNotReadyReason = evaluation.Reason{Kind: evaluation.ReasonError, ErrorKind: evaluation.ErrorKindClientNotReady}
+
 // NewCfClient creates a new client instance that connects to CF with the default configuration.
 // For advanced configuration options use ConfigOptions functions
 func NewCfClient(sdkKey string, options ...ConfigOption) (*CfClient, error) {
@@ -79,7 +91,15 @@ func NewCfClient(sdkKey string, options ...ConfigOption) (*CfClient, error) {
 		opt(config)
 	}
 
-	analyticsService := analyticsservice.NewAnalyticsService(time.Minute, config.Logger, config.seenTargetsMaxSize, config.seenTargetsClearInterval)
+	analyticsService := analyticsservice.NewAnalyticsServiceWithOptions(config.analyticsInterval, config.Logger, config.seenTargetsMaxSize,
+		config.seenTargetsClearInterval, analyticsservice.WithMaxEvaluationMetrics(config.maxEvaluationMetrics),
+		analyticsservice.WithMaxTargetMetrics(config.maxTargetMetrics))
+	if config.analyticsSpool != nil {
+		analyticsService.SetSpool(config.analyticsSpool, config.analyticsSpoolMaxAge)
+	}
+	for _, exporter := range config.analyticsExporters {
+		analyticsService.AddExporter(exporter)
+	}
 
 	client := &CfClient{
 		sdkKey:                 sdkKey,
@@ -91,47 +111,102 @@ func NewCfClient(sdkKey string, options ...ConfigOption) (*CfClient, error) {
 		stop:                   make(chan struct{}),
 		stopped:                newAtomicBool(false),
 		initializedChan:        make(chan struct{}),
-		initializedErrChan:     make(chan error),
+		initializedErrChan:     make(chan error, 1),
+		retrievedChan:          make(chan struct{}),
 		streamConnectedChan:    make(chan struct{}),
 		streamDisconnectedChan: make(chan error),
+		changeNotifier:         newFlagChangeNotifier(config.Logger),
 	}
 
-	if sdkKey == "" {
+	// offline mode and shared cache readers never contact the Feature Flag service
+	standalone := config.offlinePath != "" || config.sharedCacheReader
+
+	if sdkKey == "" && !standalone {
 		config.Logger.Errorf("%s Initialization failed: SDK Key cannot be empty. Please provide a valid SDK Key to initialize the client.", sdk_codes.InitMissingKey)
 		return client, EmptySDKKeyError
 	}
 
 	var err error
 
-	lruCache, err := repository.NewLruCache(10000)
+	// the repository holds flags and segments in the cache passed with WithCache, or the default LRU cache
+	var callback repository.Callback = client.changeNotifier
+	if config.enableStore && config.Store == nil && !standalone {
+		config.Store = defaultStore(sdkKey, config.Logger)
+	}
+	if config.enableStore && config.Store != nil && !standalone {
+		client.persister = newStorePersister(config.Store, sdkKey, config.Logger)
+		callback = repositoryCallbacks{client.changeNotifier, client.persister}
+	}
+	client.repository = repository.NewWithStorageAndCallback(config.Cache, nil, callback)
+
+	// evaluations aren't sent to analytics in offline mode or by shared cache readers
+	var postEvalCallback evaluation.PostEvaluateCallback = client
+	if standalone {
+		postEvalCallback = nil
+	}
+	client.evaluator, err = evaluation.NewEvaluatorWithOperators(client.repository, postEvalCallback, config.Logger, config.customOperators)
 	if err != nil {
 		return nil, err
 	}
-	client.repository = repository.New(lruCache)
-
-	if client.config != nil {
-		client.repository = repository.New(config.Cache)
+	client.changeNotifier.repository = client.repository
+	if client.persister != nil {
+		client.persister.repository = client.repository
 	}
-
-	client.evaluator, err = evaluation.NewEvaluator(client.repository, client, config.Logger)
+	client.changeNotifier.evaluator, err = evaluation.NewEvaluatorWithOperators(client.repository, nil, config.Logger, config.customOperators)
 	if err != nil {
 		return nil, err
 	}
 
+	if config.offlinePath != "" {
+		// offline mode never authenticates, polls, streams or sends analytics
+		if err := client.loadOffline(config.offlinePath); err != nil {
+			config.Logger.Errorf("Initialization failed: '%v'", err)
+			return client, err
+		}
+		config.Logger.Infof("%s The SDK has successfully initialized in offline mode", sdk_codes.InitSuccess)
+		return client, nil
+	}
+
+	if config.sharedCacheReader {
+		// a shared cache reader serves the flags written to the cache by another instance, it never
+		// authenticates, polls, streams or sends analytics
+		if err := client.loadSharedCache(); err != nil {
+			config.Logger.Errorf("Initialization failed: '%v'", err)
+			return client, err
+		}
+		config.Logger.Infof("%s The SDK has successfully initialized as a shared cache reader", sdk_codes.InitSuccess)
+		return client, nil
+	}
+
+	// serve the last-known-good flags from the store until they can be retrieved, so an outage during a
+	// restart doesn't result in default variations
+	loadedFromStore := client.persister != nil && client.loadStore()
+
 	client.start()
 	if config.waitForInitialized {
 		config.Logger.Infof("%s The SDK is waiting for initialization to complete'", sdk_codes.InitWaiting)
 
+		// the client is already initialized with the stored flags, but still wait for the latest flags to be
+		// retrieved so they're served from the start. The stored flags are kept if they can't be retrieved.
+		ready := client.initializedChan
+		if loadedFromStore {
+			ready = client.retrievedChan
+		}
+
 		var initErr error
 
 		select {
-		case <-client.initializedChan:
+		case <-ready:
 			config.Logger.Infof("%s The SDK has successfully initialized", sdk_codes.InitSuccess)
 			return client, nil
 		case err := <-client.initializedErrChan:
 			initErr = err
 		}
 
+		if loadedFromStore {
+			config.Logger.Warnf("%s The SDK has initialized using flags loaded from the store, as the latest flags couldn't be retrieved: '%v'", sdk_codes.InitSuccess, initErr)
+			return client, nil
+		}
 		if initErr != nil {
 			config.Logger.Errorf("Initialization failed: '%v'", initErr)
 			// We return the client but leave it in un-initialized state by not setting the relevant initialized flag.
@@ -141,6 +216,9 @@ func NewCfClient(sdkKey string, options ...ConfigOption) (*CfClient, error) {
 		}
 	}
 
+	if loadedFromStore {
+		config.Logger.Infof("%s The SDK has initialized using flags loaded from the store", sdk_codes.InitSuccess)
+	}
 	return client, nil
 }
 
@@ -152,16 +230,38 @@ func (c *CfClient) start() {
 		cancel()
 	}()
 
-	go func() {
-		if err := c.initAuthentication(context.Background()); err != nil {
+	c.spawn(func() {
+		if err := c.initAuthentication(ctx); err != nil {
 			c.config.Logger.Errorf("%s The SDK has failed to initialize due to an authentication error:  %v' ", sdk_codes.InitAuthError, err)
 			c.initializedErrChan <- err
 		}
-	}()
-	go c.setAnalyticsServiceClient(ctx)
-	go c.pullCronJob(ctx)
+	})
+	c.spawn(func() { c.setAnalyticsServiceClient(ctx) })
+	c.spawn(func() { c.pullCronJob(ctx) })
+	if c.persister != nil {
+		c.spawn(func() { c.persister.run(ctx) })
+	}
 	if c.config.enableStream {
-		go c.stream(ctx)
+		c.spawn(func() { c.stream(ctx) })
+	}
+}
+
+// spawn runs fn in a goroutine that CloseWithContext waits for
+func (c *CfClient) spawn(fn func()) {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		fn()
+	}()
+}
+
+// waitForAuthentication blocks until the client has authenticated, it returns false if ctx is cancelled first
+func (c *CfClient) waitForAuthentication(ctx context.Context) bool {
+	select {
+	case <-c.authenticatedChan:
+		return true
+	case <-ctx.Done():
+		return false
 	}
 }
 
@@ -231,13 +331,21 @@ func (c *CfClient) retrieve(ctx context.Context) {
 		c.config.Logger.Info("Data poll finished successfully")
 	}
 
+	c.markInitialized()
+	c.retrievedOnce.Do(func() { close(c.retrievedChan) })
+}
+
+// markInitialized marks the client as "initialized" once flags and segments have been loaded. It's also
+// called by the polling thread, so it checks if the client is already initialized before marking it as
+// such, and does nothing once the client has been closed. Flag change listeners are notified from then on.
+func (c *CfClient) markInitialized() {
 	c.initializedBoolLock.Lock()
 	defer c.initializedBoolLock.Unlock()
-
-	// This function is used to mark the client as "initialized" once flags and segments have been loaded,
-	// but it's also used for the polling thread, so we check if the client is already initialized before
-	// marking it as such.
+	if c.stopped.get() {
+		return
+	}
 	if !c.initializedBool {
+		c.changeNotifier.seed()
 		c.initializedBool = true
 		close(c.initializedChan)
 	}
@@ -251,7 +359,9 @@ func (c *CfClient) streamConnect(ctx context.Context) {
 		return
 	}
 
-	<-c.authenticatedChan
+	if !c.waitForAuthentication(ctx) {
+		return
+	}
 
 	c.mux.RLock()
 	defer c.mux.RUnlock()
@@ -267,6 +377,8 @@ func (c *CfClient) streamConnect(ctx context.Context) {
 	// while this is happening we set streamConnectedBool to true - if any errors happen
 	// in this process streamConnectedBool will be set back to false by the streamDisconnected function
 	conn.Connect(ctx, c.environmentID, c.sdkKey)
+	// the stream goroutines exit once ctx is cancelled, CloseWithContext waits for them
+	c.spawn(conn.Wait)
 }
 
 func (c *CfClient) initAuthentication(ctx context.Context) error {
@@ -398,7 +510,11 @@ func (c *CfClient) authenticate(ctx context.Context) error {
 	}
 	customTrans := NewCustomTransport(baseTransport, getHeadersFn)
 
-	c.config.httpClient.Transport = customTrans
+	// Copy the http client rather than changing the one passed to WithHTTPClient, it may be shared with other
+	// clients that are still authenticating
+	sdkHTTPClient := *c.config.httpClient
+	sdkHTTPClient.Transport = customTrans
+	c.config.httpClient = &sdkHTTPClient
 
 	restClient, err := rest.NewClientWithResponses(c.config.url,
 		rest.WithRequestEditorFn(bearerTokenProvider.Intercept),
@@ -431,7 +547,11 @@ func (c *CfClient) makeTicker(interval uint) *time.Ticker {
 
 func (c *CfClient) stream(ctx context.Context) {
 	// wait until initialized with initial state
-	<-c.initializedChan
+	select {
+	case <-c.initializedChan:
+	case <-ctx.Done():
+		return
+	}
 	c.streamConnect(ctx)
 
 	streamingRetryStrategy := c.config.streamingRetryStrategy
@@ -506,7 +626,9 @@ func (c *CfClient) pullCronJob(ctx context.Context) {
 		}
 	}
 	// wait until authenticated
-	<-c.authenticatedChan
+	if !c.waitForAuthentication(ctx) {
+		return
+	}
 
 	c.config.Logger.Infof("%s Polling started, interval: %v seconds", sdk_codes.PollStart, c.config.pullInterval)
 	// pull initial data
@@ -547,6 +669,9 @@ func (c *CfClient) retrieveFlags(ctx context.Context) error {
 	for _, flag := range *flags.JSON200 {
 		c.repository.SetFlag(flag, true)
 	}
+	for _, err := range evaluation.ValidatePrerequisites(c.repository.GetFlag, *flags.JSON200...) {
+		c.config.Logger.Warnf("Invalid flag prerequisites, the off variation will be served: %s", err)
+	}
 	c.config.Logger.Info("Retrieving flags finished")
 	return nil
 }
@@ -581,11 +706,17 @@ func (c *CfClient) retrieveSegments(ctx context.Context) error {
 
 func (c *CfClient) setAnalyticsServiceClient(ctx context.Context) {
 
-	<-c.authenticatedChan
+	if !c.waitForAuthentication(ctx) {
+		return
+	}
 	c.mux.RLock()
 	defer c.mux.RUnlock()
 	if !c.config.enableAnalytics {
 		c.config.Logger.Info("Posting analytics data disabled")
+		// exporters still receive the metrics
+		if len(c.config.analyticsExporters) > 0 {
+			c.analyticsService.Start(ctx, nil, c.environmentID)
+		}
 		return
 	}
 	c.config.Logger.Info("Posting analytics data enabled")
@@ -595,68 +726,105 @@ func (c *CfClient) setAnalyticsServiceClient(ctx context.Context) {
 // BoolVariation returns the value of a boolean feature flag for a given target.
 // Returns defaultValue if there is an error or if the flag doesn't exist
 func (c *CfClient) BoolVariation(key string, target *evaluation.Target, defaultValue bool) (bool, error) {
+	detail, err := c.BoolVariationDetail(key, target, defaultValue)
+	return detail.Value, err
+}
+
+// BoolVariationDetail returns the value of a boolean feature flag for a given target, along with
+// the variation that was served and the reason it was chosen.
+//
+// Returns defaultValue and an error reason if there is an error or if the flag doesn't exist
+func (c *CfClient) BoolVariationDetail(key string, target *evaluation.Target, defaultValue bool) (evaluation.EvaluationDetail[bool], error) {
 	if !c.initializedBool {
 		c.config.Logger.Infof("%s Error while evaluating boolean flag and returning default variation: 'Client is not initialized'", sdk_codes.EvaluationFailed)
-		return defaultValue, fmt.Errorf("%w: Client is not initialized", DefaultVariationReturnedError)
+		return evaluation.EvaluationDetail[bool]{Value: defaultValue, Reason: clientNotReadyReason}, fmt.Errorf("%w: Client is not initialized", DefaultVariationReturnedError)
 	}
-	value, err := c.evaluator.BoolVariation(key, target, defaultValue)
+	detail, err := c.evaluator.BoolVariationDetail(key, target, defaultValue)
 	if err != nil {
 		c.config.Logger.Infof("%s Error while evaluating boolean flag and returning default variation '%s', err: %v", sdk_codes.EvaluationFailed, key, err)
-		return value, fmt.Errorf("%w: `%v`", DefaultVariationReturnedError, err)
+		return detail, fmt.Errorf("%w: `%v`", DefaultVariationReturnedError, err)
 	}
 	c.config.Logger.Debugf("%s Evaluated boolean flag successfully: '%s'", sdk_codes.EvaluationSuccess, key)
-	return value, nil
+	return detail, nil
 }
 
 // StringVariation returns the value of a string feature flag for a given target.
 //
 // Returns defaultValue if there is an error or if the flag doesn't exist
 func (c *CfClient) StringVariation(key string, target *evaluation.Target, defaultValue string) (string, error) {
+	detail, err := c.StringVariationDetail(key, target, defaultValue)
+	return detail.Value, err
+}
+
+// StringVariationDetail returns the value of a string feature flag for a given target, along with
+// the variation that was served and the reason it was chosen.
+//
+// Returns defaultValue and an error reason if there is an error or if the flag doesn't exist
+func (c *CfClient) StringVariationDetail(key string, target *evaluation.Target, defaultValue string) (evaluation.EvaluationDetail[string], error) {
 	if !c.initializedBool {
 		c.config.Logger.Infof("%s Error while evaluating string flag and returning default variation: 'Client is not initialized'", sdk_codes.EvaluationFailed)
-		return defaultValue, fmt.Errorf("%w: Client is not initialized", DefaultVariationReturnedError)
+		return evaluation.EvaluationDetail[string]{Value: defaultValue, Reason: clientNotReadyReason}, fmt.Errorf("%w: Client is not initialized", DefaultVariationReturnedError)
 	}
-	value, err := c.evaluator.StringVariation(key, target, defaultValue)
+	detail, err := c.evaluator.StringVariationDetail(key, target, defaultValue)
 	if err != nil {
 		c.config.Logger.Infof("%s Error while evaluating string flag '%s', err: %v", sdk_codes.EvaluationFailed, key, err)
-		return value, fmt.Errorf("%w: `%v`", DefaultVariationReturnedError, err)
+		return detail, fmt.Errorf("%w: `%v`", DefaultVariationReturnedError, err)
 	}
 	c.config.Logger.Debugf("%s Evaluated string flag successfully: '%s'", sdk_codes.EvaluationSuccess, key)
-	return value, nil
+	return detail, nil
 }
 
 // IntVariation returns the value of a integer feature flag for a given target.
 //
 // Returns defaultValue if there is an error or if the flag doesn't exist
 func (c *CfClient) IntVariation(key string, target *evaluation.Target, defaultValue int64) (int64, error) {
+	detail, err := c.IntVariationDetail(key, target, defaultValue)
+	return detail.Value, err
+}
+
+// IntVariationDetail returns the value of a integer feature flag for a given target, along with
+// the variation that was served and the reason it was chosen.
+//
+// Returns defaultValue and an error reason if there is an error or if the flag doesn't exist
+func (c *CfClient) IntVariationDetail(key string, target *evaluation.Target, defaultValue int64) (evaluation.EvaluationDetail[int64], error) {
 	if !c.initializedBool {
 		c.config.Logger.Infof("%s Error while evaluating int flag and returning default variation: 'Client is not initialized'", sdk_codes.EvaluationFailed)
-		return defaultValue, fmt.Errorf("%w: Client is not initialized", DefaultVariationReturnedError)
+		return evaluation.EvaluationDetail[int64]{Value: defaultValue, Reason: clientNotReadyReason}, fmt.Errorf("%w: Client is not initialized", DefaultVariationReturnedError)
 	}
-	value, err := c.evaluator.IntVariation(key, target, int(defaultValue))
+	detail, err := c.evaluator.IntVariationDetail(key, target, int(defaultValue))
+	result := evaluation.EvaluationDetail[int64]{Value: int64(detail.Value), Variation: detail.Variation, Reason: detail.Reason}
 	if err != nil {
 		c.config.Logger.Infof("%s Error while evaluating int flag '%s', err: %v", sdk_codes.EvaluationFailed, key, err)
-		return int64(value), fmt.Errorf("%w: `%v`", DefaultVariationReturnedError, err)
+		return result, fmt.Errorf("%w: `%v`", DefaultVariationReturnedError, err)
 	}
 	c.config.Logger.Debugf("%s Evaluated int flag successfully: '%s'", sdk_codes.EvaluationSuccess, key)
-	return int64(value), nil
+	return result, nil
 }
 
 // NumberVariation returns the value of a float64 feature flag for a given target.
 //
 // Returns defaultValue if there is an error or if the flag doesn't exist
 func (c *CfClient) NumberVariation(key string, target *evaluation.Target, defaultValue float64) (float64, error) {
+	detail, err := c.NumberVariationDetail(key, target, defaultValue)
+	return detail.Value, err
+}
+
+// NumberVariationDetail returns the value of a float64 feature flag for a given target, along with
+// the variation that was served and the reason it was chosen.
+//
+// Returns defaultValue and an error reason if there is an error or if the flag doesn't exist
+func (c *CfClient) NumberVariationDetail(key string, target *evaluation.Target, defaultValue float64) (evaluation.EvaluationDetail[float64], error) {
 	if !c.initializedBool {
 		c.config.Logger.Infof("%s Error while number number flag and returning default variation: 'Client is not initialized'", sdk_codes.EvaluationFailed)
-		return defaultValue, fmt.Errorf("%w: Client is not initialized", DefaultVariationReturnedError)
+		return evaluation.EvaluationDetail[float64]{Value: defaultValue, Reason: clientNotReadyReason}, fmt.Errorf("%w: Client is not initialized", DefaultVariationReturnedError)
 	}
-	value, err := c.evaluator.NumberVariation(key, target, defaultValue)
+	detail, err := c.evaluator.NumberVariationDetail(key, target, defaultValue)
 	if err != nil {
 		c.config.Logger.Infof("%s Error while evaluating number flag '%s', err: %v", sdk_codes.EvaluationFailed, key, err)
-		return value, fmt.Errorf("%w: `%v`", DefaultVariationReturnedError, err)
+		return detail, fmt.Errorf("%w: `%v`", DefaultVariationReturnedError, err)
 	}
 	c.config.Logger.Debugf("%s Evaluated number flag successfully: '%s'", sdk_codes.EvaluationSuccess, key)
-	return value, nil
+	return detail, nil
 }
 
 // JSONVariation returns the value of a feature flag for the given target, allowing the value to be
@@ -664,22 +832,73 @@ func (c *CfClient) NumberVariation(key string, target *evaluation.Target, defaul
 //
 // Returns defaultValue if there is an error or if the flag doesn't exist
 func (c *CfClient) JSONVariation(key string, target *evaluation.Target, defaultValue types.JSON) (types.JSON, error) {
+	detail, err := c.JSONVariationDetail(key, target, defaultValue)
+	return detail.Value, err
+}
+
+// JSONVariationDetail returns the value of a JSON feature flag for a given target, along with
+// the variation that was served and the reason it was chosen.
+//
+// Returns defaultValue and an error reason if there is an error or if the flag doesn't exist
+func (c *CfClient) JSONVariationDetail(key string, target *evaluation.Target, defaultValue types.JSON) (evaluation.EvaluationDetail[types.JSON], error) {
 	if !c.initializedBool {
 		c.config.Logger.Infof("%s Error while evaluating json flag and returning default variation: 'Client is not initialized'", sdk_codes.EvaluationFailed)
-		return defaultValue, fmt.Errorf("%w: Client is not initialized", DefaultVariationReturnedError)
+		return evaluation.EvaluationDetail[types.JSON]{Value: defaultValue, Reason: clientNotReadyReason}, fmt.Errorf("%w: Client is not initialized", DefaultVariationReturnedError)
 	}
-	value, err := c.evaluator.JSONVariation(key, target, defaultValue)
+	detail, err := c.evaluator.JSONVariationDetail(key, target, defaultValue)
+	result := evaluation.EvaluationDetail[types.JSON]{Value: detail.Value, Variation: detail.Variation, Reason: detail.Reason}
 	if err != nil {
 		c.config.Logger.Infof("%s Error while evaluating json flag '%s', err: %v", sdk_codes.EvaluationFailed, key, err)
-		return value, fmt.Errorf("%w: `%v`", DefaultVariationReturnedError, err)
+		return result, fmt.Errorf("%w: `%v`", DefaultVariationReturnedError, err)
 	}
 	c.config.Logger.Debugf("%s Evaluated json flag successfully: '%s'", sdk_codes.EvaluationSuccess, key)
-	return value, nil
+	return result, nil
+}
+
+// EvaluateAll returns the variation of every flag for the given target, e.g. to bootstrap a front-end
+// with a single server-side evaluation pass. Flags that fail to evaluate are logged and returned with
+// an empty variation.
+func (c *CfClient) EvaluateAll(target *evaluation.Target) (evaluation.FlagVariations, error) {
+	if !c.initializedBool {
+		c.config.Logger.Infof("%s Error while evaluating all flags: 'Client is not initialized'", sdk_codes.EvaluationFailed)
+		return nil, fmt.Errorf("%w: Client is not initialized", DefaultVariationReturnedError)
+	}
+	variations, err := c.evaluator.EvaluateAll(target)
+	if err != nil {
+		c.config.Logger.Infof("%s Error while evaluating all flags, err: %v", sdk_codes.EvaluationFailed, err)
+		return variations, err
+	}
+	c.config.Logger.Debugf("%s Evaluated %d flags successfully", sdk_codes.EvaluationSuccess, len(variations))
+	return variations, nil
+}
+
+// OnFlagChange registers a listener that is called when polling or streaming changes a stored flag, or a
+// target segment that the flag uses. Listeners are called synchronously and should return quickly.
+func (c *CfClient) OnFlagChange(fn func(event FlagChangeEvent)) {
+	c.changeNotifier.addListener(fn)
+}
+
+// OnFlagValueChange registers a listener that is called when the variation served to target for flag
+// changes, e.g. because the flag, a segment or a prerequisite was updated. An empty variation is passed
+// if the flag doesn't exist. Listeners are called synchronously and should return quickly.
+func (c *CfClient) OnFlagValueChange(flag string, target *evaluation.Target, fn func(old, new rest.Variation)) {
+	c.changeNotifier.addValueListener(flag, target, fn)
 }
 
 // Close shuts down the Feature Flag client. After calling this, the client
-// should no longer be used
+// should no longer be used. It waits up to five seconds for pending analytics
+// to be sent, use CloseWithContext to choose how long to wait.
 func (c *CfClient) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultCloseTimeout)
+	defer cancel()
+	return c.CloseWithContext(ctx)
+}
+
+// CloseWithContext shuts down the Feature Flag client, waits for the polling and
+// streaming goroutines to exit and sends the analytics that haven't been sent yet.
+// If ctx is done first the client is still shut down, but pending analytics may
+// be lost and ctx.Err() is returned.
+func (c *CfClient) CloseWithContext(ctx context.Context) error {
 	if !c.initializedBool {
 		return errors.New("attempted to close client that is not initialized")
 	}
@@ -695,11 +914,37 @@ func (c *CfClient) Close() error {
 	c.initializedBoolLock.Lock()
 	c.initializedBool = false
 	c.initializedBoolLock.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		c.config.Logger.Warnf("Timed out waiting for the SDK to stop, pending analytics have not been sent: %v", ctx.Err())
+		return ctx.Err()
+	}
+
+	if c.config.enableAnalytics || len(c.config.analyticsExporters) > 0 {
+		if err := c.analyticsService.Flush(ctx); err != nil {
+			c.config.Logger.Warnf("Timed out sending pending analytics: %v", err)
+			return err
+		}
+	}
 	c.config.Logger.Infof("%s SDK Closed successfully", sdk_codes.CloseSuccess)
 
 	return nil
 }
 
+// DroppedAnalyticsEvents returns how many evaluations weren't counted in analytics because the analytics
+// queue was full. Evaluations never wait for the queue, so a growing count means evaluations are happening
+// faster than they can be aggregated.
+func (c *CfClient) DroppedAnalyticsEvents() uint64 {
+	return c.analyticsService.DroppedEvents()
+}
+
 // Environment returns environment based on authenticated SDK flagIdentifier
 func (c *CfClient) Environment() string {
 	return c.environmentID
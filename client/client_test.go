@@ -2,22 +2,37 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
 	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/cenkalti/backoff/v4"
+	"github.com/harness/ff-golang-server-sdk/analyticsservice"
+	"github.com/harness/ff-golang-server-sdk/cache"
 	"github.com/harness/ff-golang-server-sdk/dto"
 	"github.com/harness/ff-golang-server-sdk/evaluation"
 	"github.com/harness/ff-golang-server-sdk/log"
+	"github.com/harness/ff-golang-server-sdk/logger"
+	"github.com/harness/ff-golang-server-sdk/metricsclient"
+	"github.com/harness/ff-golang-server-sdk/pkg/repository"
+	"github.com/harness/ff-golang-server-sdk/pkg/repository/rediscache"
 	"github.com/harness/ff-golang-server-sdk/rest"
+	"github.com/harness/ff-golang-server-sdk/storage"
 	"github.com/harness/ff-golang-server-sdk/test_helpers"
 	"github.com/harness/ff-golang-server-sdk/types"
 	"github.com/jarcoal/httpmock"
+	"github.com/mitchellh/go-homedir"
+	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -455,6 +470,555 @@ func TestCfClient_StringVariation(t *testing.T) {
 	}
 }
 
+func TestCfClient_EvaluateAll(t *testing.T) {
+	authSuccessResponse := AuthResponse(200, ValidAuthToken)
+	registerResponders(authSuccessResponse, TargetSegmentsResponse, FeatureConfigsResponse)
+	client, target, err := MakeNewSynchronousClientAndTarget(ValidSDKKey)
+	if err != nil {
+		t.Error(err)
+	}
+
+	variations, err := client.EvaluateAll(target)
+	assert.Nil(t, err)
+
+	got := map[string]string{}
+	for _, v := range variations {
+		got[v.FlagIdentifier] = v.Variation.Identifier
+	}
+	assert.Equal(t, "true", got["TestTrueOn"])
+	assert.Equal(t, "false", got["TestTrueOff"])
+	assert.Equal(t, "false", got["TestTrueOnWithPreReqFalse"])
+	assert.Equal(t, "Alpha", got["TestStringAOn"])
+	assert.Equal(t, "Bravo", got["TestStringAOff"])
+
+	t.Run("Uninitialized client returns an error", func(t *testing.T) {
+		uninitialized, _ := newClient(http.DefaultClient, EmptySDKKey)
+		variations, err := uninitialized.EvaluateAll(target)
+		assert.True(t, errors.Is(err, DefaultVariationReturnedError))
+		assert.Empty(t, variations)
+	})
+}
+
+func TestCfClient_OnFlagChange(t *testing.T) {
+	var mu sync.Mutex
+	flags := test_helpers.MakeBoolFeatureConfigs("TestTrueOn", "true", "false", "on")
+	setFlag := func(update func(flag *rest.FeatureConfig)) {
+		mu.Lock()
+		defer mu.Unlock()
+		update(&flags[0])
+	}
+
+	// the first poll waits until the listeners have been registered
+	registered := make(chan struct{})
+	featureConfigs := func(req *http.Request) (*http.Response, error) {
+		<-registered
+		mu.Lock()
+		defer mu.Unlock()
+		return httpmock.NewJsonResponse(200, flags)
+	}
+	registerResponders(AuthResponse(200, ValidAuthToken), TargetSegmentsResponse, featureConfigs)
+
+	// the stream sends a single delete event once the flag updates have been polled
+	deleted := make(chan struct{})
+	var streams int
+	httpmock.RegisterResponder("GET", "http://localhost/api/1.0/stream", func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		streams++
+		first := streams == 1
+		mu.Unlock()
+		if !first {
+			return httpmock.NewStringResponse(503, ""), nil
+		}
+		<-deleted
+		resp := httpmock.NewStringResponse(200, "event: *\ndata: {\"domain\":\"flag\",\"event\":\"delete\",\"identifier\":\"TestTrueOn\"}\n\n")
+		resp.Header.Set("Content-Type", "text/event-stream")
+		return resp, nil
+	})
+
+	client, err := newClient(http.DefaultClient, ValidSDKKey, WithStreamEnabled(true))
+	assert.Nil(t, err)
+	defer client.Close()
+
+	var events []FlagChangeEvent
+	client.OnFlagChange(func(event FlagChangeEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	})
+	type valueChange struct{ old, new string }
+	var changes []valueChange
+	client.OnFlagValueChange("TestTrueOn", target(), func(old, new rest.Variation) {
+		mu.Lock()
+		defer mu.Unlock()
+		changes = append(changes, valueChange{old.Identifier, new.Identifier})
+	})
+	recorded := func() ([]FlagChangeEvent, []valueChange) {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]FlagChangeEvent{}, events...), append([]valueChange{}, changes...)
+	}
+	close(registered)
+
+	// listeners registered before the initial poll aren't notified of the flags it loads
+	select {
+	case <-client.initializedChan:
+	case <-time.After(time.Second):
+		t.Fatal("the client wasn't initialized")
+	}
+	gotEvents, gotChanges := recorded()
+	assert.Empty(t, gotEvents)
+	assert.Empty(t, gotChanges)
+
+	// polling re-stores unchanged flags, which shouldn't notify
+	client.retrieve(context.Background())
+	gotEvents, gotChanges = recorded()
+	assert.Empty(t, gotEvents)
+	assert.Empty(t, gotChanges)
+
+	// an update that doesn't change the served variation only notifies change listeners
+	setFlag(func(flag *rest.FeatureConfig) {
+		v2 := int64(2)
+		flag.Version = &v2
+	})
+	client.retrieve(context.Background())
+	gotEvents, gotChanges = recorded()
+	assert.Equal(t, []FlagChangeEvent{{Flag: "TestTrueOn"}}, gotEvents)
+	assert.Empty(t, gotChanges)
+
+	setFlag(func(flag *rest.FeatureConfig) {
+		v3 := int64(3)
+		flag.Version = &v3
+		flag.State = rest.FeatureStateOff
+	})
+	client.retrieve(context.Background())
+	gotEvents, gotChanges = recorded()
+	assert.Equal(t, []FlagChangeEvent{{Flag: "TestTrueOn"}, {Flag: "TestTrueOn"}}, gotEvents)
+	assert.Equal(t, []valueChange{{"true", "false"}}, gotChanges)
+
+	close(deleted)
+	assert.Eventually(t, func() bool {
+		gotEvents, _ := recorded()
+		return len(gotEvents) == 3
+	}, time.Second, 10*time.Millisecond)
+	gotEvents, gotChanges = recorded()
+	assert.Equal(t, FlagChangeEvent{Flag: "TestTrueOn", Deleted: true}, gotEvents[len(gotEvents)-1])
+	assert.Equal(t, []valueChange{{"true", "false"}, {"false", ""}}, gotChanges)
+}
+
+func TestCfClient_OfflineMode(t *testing.T) {
+	for _, path := range []string{"testdata/offline.json", "testdata/offline.yaml"} {
+		path := path
+		t.Run(path, func(t *testing.T) {
+			// no responders are registered, so any request to the Feature Flag service would fail
+			httpmock.Reset()
+			client, err := NewCfClient("", WithOfflineMode(path), WithHTTPClient(http.DefaultClient))
+			assert.Nil(t, err)
+			defer client.Close()
+
+			ok, err := client.IsInitialized()
+			assert.True(t, ok)
+			assert.Nil(t, err)
+
+			beta, err := client.BoolVariation("darkMode", &evaluation.Target{Identifier: "beta_user"}, false)
+			assert.Nil(t, err)
+			assert.True(t, beta)
+
+			other, err := client.BoolVariation("darkMode", &evaluation.Target{Identifier: "other"}, true)
+			assert.Nil(t, err)
+			assert.False(t, other)
+
+			greeting, err := client.StringVariation("greeting", &evaluation.Target{Identifier: "other"}, "default")
+			assert.Nil(t, err)
+			assert.Equal(t, "hello", greeting)
+			assert.Equal(t, 0, httpmock.GetTotalCallCount())
+		})
+	}
+
+	t.Run("missing snapshot file", func(t *testing.T) {
+		client, err := NewCfClient("", WithOfflineMode("testdata/missing.json"))
+		assert.True(t, errors.Is(err, OfflineSnapshotError))
+		_, err = client.BoolVariation("darkMode", &evaluation.Target{Identifier: "beta_user"}, false)
+		assert.True(t, errors.Is(err, DefaultVariationReturnedError))
+	})
+}
+
+func TestCfClient_OfflineWatch(t *testing.T) {
+	dir := t.TempDir()
+	snapshot, err := os.ReadFile("testdata/offline.json")
+	assert.Nil(t, err)
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "flags.json"), snapshot, 0600))
+	// files that aren't snapshots are ignored
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("not flags"), 0600))
+
+	client, err := NewCfClient("", WithOfflineMode(dir), WithOfflineWatch(10*time.Millisecond))
+	assert.Nil(t, err)
+	defer client.Close()
+
+	var mu sync.Mutex
+	var events []FlagChangeEvent
+	client.OnFlagChange(func(event FlagChangeEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	})
+	target := &evaluation.Target{Identifier: "other"}
+	greeting, _ := client.StringVariation("greeting", target, "default")
+	assert.Equal(t, "hello", greeting)
+
+	// an invalid edit keeps the previous flags
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "flags.json"), []byte("{"), 0600))
+	time.Sleep(50 * time.Millisecond)
+	greeting, _ = client.StringVariation("greeting", target, "default")
+	assert.Equal(t, "hello", greeting)
+
+	// so does an edit whose prerequisites form a cycle, it's validated before any flag is replaced
+	var cyclic map[string]interface{}
+	assert.Nil(t, json.Unmarshal(snapshot, &cyclic))
+	cyclicFlags := cyclic["flags"].([]interface{})
+	cyclicFlags[0].(map[string]interface{})["prerequisites"] = []interface{}{map[string]interface{}{"feature": "greeting", "variations": []string{"hello"}}}
+	cyclicFlags[1].(map[string]interface{})["prerequisites"] = []interface{}{map[string]interface{}{"feature": "darkMode", "variations": []string{"true"}}}
+	cyclicFlags[1].(map[string]interface{})["state"] = "off"
+	content, err := json.Marshal(cyclic)
+	assert.Nil(t, err)
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "flags.json"), content, 0600))
+	time.Sleep(50 * time.Millisecond)
+	greeting, _ = client.StringVariation("greeting", target, "default")
+	assert.Equal(t, "hello", greeting)
+
+	// turn greeting off without bumping its version, and remove darkMode
+	var updated map[string]interface{}
+	assert.Nil(t, json.Unmarshal(snapshot, &updated))
+	flags := updated["flags"].([]interface{})
+	greetingFlag := flags[1].(map[string]interface{})
+	greetingFlag["state"] = "off"
+	updated["flags"] = []interface{}{greetingFlag}
+	content, err = json.Marshal(updated)
+	assert.Nil(t, err)
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "flags.json"), content, 0600))
+
+	assert.Eventually(t, func() bool {
+		greeting, _ := client.StringVariation("greeting", target, "default")
+		return greeting == "hi"
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return assert.ObjectsAreEqual([]FlagChangeEvent{{Flag: "greeting"}, {Flag: "darkMode", Deleted: true}}, events)
+	}, time.Second, 10*time.Millisecond)
+
+	_, err = client.BoolVariation("darkMode", target, false)
+	assert.True(t, errors.Is(err, DefaultVariationReturnedError))
+}
+
+func TestCfClient_SharedCacheReader(t *testing.T) {
+	server := miniredis.RunT(t)
+	newCache := func() *rediscache.RedisCache {
+		return rediscache.NewRedisCache(redis.NewClient(&redis.Options{Addr: server.Addr()}), rediscache.WithRedisPrefix("test:"))
+	}
+
+	registerResponders(AuthResponse(200, ValidAuthToken), TargetSegmentsResponse, FeatureConfigsResponse)
+	writer, err := newClient(http.DefaultClient, ValidSDKKey, WithWaitForInitialized(true), WithCache(newCache()))
+	assert.Nil(t, err)
+	defer writer.Close()
+
+	calls := httpmock.GetTotalCallCount()
+	reader, err := NewCfClient("", WithSharedCacheReader(), WithCache(newCache()), WithPullInterval(1),
+		WithURL(URL), WithHTTPClient(http.DefaultClient), WithStoreEnabled(false))
+	assert.Nil(t, err)
+	defer reader.Close()
+
+	flag, err := reader.BoolVariation("TestTrueOn", target(), false)
+	assert.Nil(t, err)
+	assert.True(t, flag)
+
+	// flags the writer polls are served by the reader once its cache has been refreshed
+	registerResponders(AuthResponse(200, ValidAuthToken), TargetSegmentsResponse, func(req *http.Request) (*http.Response, error) {
+		return httpmock.NewJsonResponse(200, test_helpers.MakeBoolFeatureConfigs("TestTrueOn", "true", "false", "off"))
+	})
+	writer.retrieve(context.Background())
+	assert.Eventually(t, func() bool {
+		flag, err := reader.BoolVariation("TestTrueOn", target(), true)
+		return err == nil && !flag
+	}, 3*time.Second, 50*time.Millisecond)
+
+	// the reader only evaluated, the requests were all made by the writer's poll
+	assert.Equal(t, calls+2, httpmock.GetTotalCallCount())
+
+	t.Run("The cache must be refreshable", func(t *testing.T) {
+		_, err := NewCfClient("", WithSharedCacheReader())
+		assert.True(t, errors.Is(err, SharedCacheError))
+	})
+
+	t.Run("The shared cache must be readable", func(t *testing.T) {
+		server.SetError("unavailable")
+		defer server.SetError("")
+		_, err := NewCfClient("", WithSharedCacheReader(), WithCache(newCache()))
+		assert.True(t, errors.Is(err, SharedCacheError))
+	})
+}
+
+func TestCfClient_Store(t *testing.T) {
+	dir := t.TempDir()
+	newStore := func() storage.Storage {
+		return storage.NewFileStore("test", dir, logger.NewNoOpLogger())
+	}
+
+	registerResponders(AuthResponse(200, ValidAuthToken), TargetSegmentsResponse, FeatureConfigsResponse)
+	client, err := newClient(http.DefaultClient, ValidSDKKey, WithWaitForInitialized(true),
+		WithStore(newStore()), WithStoreEnabled(true))
+	assert.Nil(t, err)
+
+	// the flags are persisted once they've been retrieved
+	assert.Eventually(t, func() bool {
+		store := newStore()
+		if store.Load() != nil {
+			return false
+		}
+		_, ok := store.Get("flag/TestTrueOn")
+		return ok
+	}, time.Second, 10*time.Millisecond)
+	assert.Nil(t, client.Close())
+
+	// a restart while the service is unavailable serves the stored flags
+	registerResponders(AuthResponseDetailed(500, "500", `{"message": "unavailable", "code": "500"}`), TargetSegmentsResponse, FeatureConfigsResponse)
+	client, err = newClient(http.DefaultClient, ValidSDKKey, WithWaitForInitialized(true),
+		WithStore(newStore()), WithStoreEnabled(true), WithAuthRetryStrategy(getInstantRetryStrategy()), WithMaxAuthRetries(1))
+	assert.Nil(t, err)
+	defer client.Close()
+
+	flag, err := client.BoolVariation("TestTrueOnWithPreReqTrue", target(), false)
+	assert.Nil(t, err)
+	assert.True(t, flag)
+	str, err := client.StringVariation("TestStringAOff", target(), "default")
+	assert.Nil(t, err)
+	assert.Equal(t, "B", str)
+
+	t.Run("Flags stored for another SDK key aren't served", func(t *testing.T) {
+		other, err := newClient(http.DefaultClient, InvaliDSDKKey, WithStore(newStore()), WithStoreEnabled(true),
+			WithAuthRetryStrategy(getInstantRetryStrategy()), WithMaxAuthRetries(1))
+		assert.Nil(t, err)
+		_, err = other.BoolVariation("TestTrueOn", target(), false)
+		assert.True(t, errors.Is(err, DefaultVariationReturnedError))
+	})
+
+	t.Run("Waiting for initialization serves the latest flags rather than the stored ones", func(t *testing.T) {
+		latest := func(req *http.Request) (*http.Response, error) {
+			return httpmock.NewJsonResponse(200, test_helpers.MakeBoolFeatureConfigs("TestTrueOn", "true", "false", "off"))
+		}
+		registerResponders(AuthResponse(200, ValidAuthToken), TargetSegmentsResponse, latest)
+		restarted, err := newClient(http.DefaultClient, ValidSDKKey, WithWaitForInitialized(true),
+			WithStore(newStore()), WithStoreEnabled(true))
+		assert.Nil(t, err)
+		defer restarted.Close()
+
+		flag, err := restarted.BoolVariation("TestTrueOn", target(), true)
+		assert.Nil(t, err)
+		assert.False(t, flag)
+	})
+
+	t.Run("An encrypted store can only be read with the key", func(t *testing.T) {
+		dir := t.TempDir()
+		key := storage.StaticKey("0123456789abcdef0123456789abcdef")
+		registerResponders(AuthResponse(200, ValidAuthToken), TargetSegmentsResponse, FeatureConfigsResponse)
+		encrypted, err := newClient(http.DefaultClient, ValidSDKKey, WithWaitForInitialized(true),
+			WithStore(storage.NewEncryptedFileStore("test", dir, key, logger.NewNoOpLogger())), WithStoreEnabled(true))
+		assert.Nil(t, err)
+		defer encrypted.Close()
+
+		path := filepath.Join(dir, "harness-ffm-v1-test.json")
+		assert.Eventually(t, func() bool {
+			_, err := os.Stat(path)
+			return err == nil
+		}, time.Second, 10*time.Millisecond)
+		content, err := os.ReadFile(path)
+		assert.Nil(t, err)
+		assert.NotContains(t, string(content), "TestTrueOn")
+
+		store := storage.NewEncryptedFileStore("test", dir, key, logger.NewNoOpLogger())
+		assert.Nil(t, store.Load())
+		flag, ok := store.Get("flag/TestTrueOn")
+		assert.True(t, ok)
+		assert.NotEmpty(t, flag)
+
+		// a wrong key returns an error and leaves the file in place rather than treating it as corrupt
+		newKey := storage.StaticKey("fedcba9876543210fedcba9876543210")
+		wrongKey := storage.NewEncryptedFileStore("test", dir, newKey, logger.NewNoOpLogger())
+		assert.True(t, errors.Is(wrongKey.Load(), storage.ErrDecryptionFailed))
+		_, ok = wrongKey.Get("flag/TestTrueOn")
+		assert.False(t, ok)
+		_, err = os.Stat(path)
+		assert.Nil(t, err)
+
+		// after rotating the key the file can still be read with the previous key
+		rotated := storage.NewEncryptedFileStore("test", dir,
+			storage.StaticKeyRing{Current: newKey, Previous: [][]byte{key}}, logger.NewNoOpLogger())
+		assert.Nil(t, rotated.Load())
+		flag, ok = rotated.Get("flag/TestTrueOn")
+		assert.True(t, ok)
+		assert.NotEmpty(t, flag)
+	})
+
+	t.Run("A bbolt store serves the stored flags after a restart", func(t *testing.T) {
+		dir := t.TempDir()
+		newBoltStore := func() storage.Storage {
+			return storage.NewBoltStore("test", dir, logger.NewNoOpLogger())
+		}
+
+		// nothing has been stored yet
+		_, err := os.Stat(filepath.Join(dir, "harness-ffm-v1-test.db"))
+		assert.True(t, os.IsNotExist(err))
+		assert.True(t, os.IsNotExist(newBoltStore().Load()))
+
+		registerResponders(AuthResponse(200, ValidAuthToken), TargetSegmentsResponse, FeatureConfigsResponse)
+		first, err := newClient(http.DefaultClient, ValidSDKKey, WithWaitForInitialized(true),
+			WithStore(newBoltStore()), WithStoreEnabled(true))
+		assert.Nil(t, err)
+		assert.Eventually(t, func() bool {
+			store := newBoltStore()
+			if store.Load() != nil {
+				return false
+			}
+			_, ok := store.Get("flag/TestTrueOn")
+			return ok
+		}, time.Second, 10*time.Millisecond)
+		assert.Nil(t, first.Close())
+
+		registerResponders(AuthResponseDetailed(500, "500", `{"message": "unavailable", "code": "500"}`), TargetSegmentsResponse, FeatureConfigsResponse)
+		restarted, err := newClient(http.DefaultClient, ValidSDKKey, WithWaitForInitialized(true),
+			WithStore(newBoltStore()), WithStoreEnabled(true), WithAuthRetryStrategy(getInstantRetryStrategy()), WithMaxAuthRetries(1))
+		assert.Nil(t, err)
+		defer restarted.Close()
+		flag, err := restarted.BoolVariation("TestTrueOn", target(), false)
+		assert.Nil(t, err)
+		assert.True(t, flag)
+	})
+
+	t.Run("A corrupt file is quarantined and the client starts without stored flags", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "harness-ffm-v1-test.json")
+		content := []byte(`{"version":1,"checksum":"abc","data":{"flags":[{"feature":"TestTr`)
+		assert.Nil(t, os.WriteFile(path, content, 0600))
+
+		corrupt, err := newClient(http.DefaultClient, ValidSDKKey,
+			WithStore(storage.NewFileStore("test", dir, logger.NewNoOpLogger())), WithStoreEnabled(true),
+			WithAuthRetryStrategy(getInstantRetryStrategy()), WithMaxAuthRetries(1))
+		assert.Nil(t, err)
+		_, err = corrupt.BoolVariation("TestTrueOn", target(), false)
+		assert.True(t, errors.Is(err, DefaultVariationReturnedError))
+
+		quarantined, err := filepath.Glob(path + ".corrupt-*")
+		assert.Nil(t, err)
+		assert.Len(t, quarantined, 1)
+		_, err = os.Stat(path)
+		assert.True(t, os.IsNotExist(err))
+	})
+}
+
+func TestDefaultStore(t *testing.T) {
+	homedir.DisableCache = true
+	defer func() { homedir.DisableCache = false }()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	t.Log("When clients with different SDK keys run on the same host they persist to different files")
+	for _, sdkKey := range []string{ValidSDKKey, InvaliDSDKKey} {
+		store := defaultStore(sdkKey, logger.NewNoOpLogger())
+		assert.Nil(t, store.Set("key", sdkKey))
+		assert.Nil(t, store.Persist())
+	}
+	files, err := filepath.Glob(filepath.Join(home, "harness", "harness-ffm-v1-sdk-*.json"))
+	assert.Nil(t, err)
+	assert.Len(t, files, 2)
+
+	store := defaultStore(ValidSDKKey, logger.NewNoOpLogger())
+	assert.Nil(t, store.Load())
+	value, _ := store.Get("key")
+	assert.Equal(t, ValidSDKKey, value)
+
+	t.Log("When DISABLE_LOCAL_CACHE is set there's no default store")
+	t.Setenv("DISABLE_LOCAL_CACHE", "true")
+	assert.Nil(t, defaultStore(ValidSDKKey, logger.NewNoOpLogger()))
+}
+
+// recordingCache is a custom cache that records the keys it's asked to store
+type recordingCache struct {
+	*cache.LRUCache
+	mu   sync.Mutex
+	sets map[interface{}]int
+}
+
+func (c *recordingCache) Set(key, value interface{}) bool {
+	c.mu.Lock()
+	c.sets[key]++
+	c.mu.Unlock()
+	return c.LRUCache.Set(key, value)
+}
+
+func TestCfClient_WithCache(t *testing.T) {
+	lru, err := cache.NewLruCache(100, logger.NewNoOpLogger())
+	assert.Nil(t, err)
+	custom := &recordingCache{LRUCache: lru, sets: map[interface{}]int{}}
+
+	registerResponders(AuthResponse(200, ValidAuthToken), TargetSegmentsResponse, FeatureConfigsResponse)
+	client, err := newClient(http.DefaultClient, ValidSDKKey, WithWaitForInitialized(true), WithCache(custom))
+	assert.Nil(t, err)
+	defer client.Close()
+
+	flag, err := client.BoolVariation("TestTrueOn", target(), false)
+	assert.Nil(t, err)
+	assert.True(t, flag)
+
+	custom.mu.Lock()
+	defer custom.mu.Unlock()
+	assert.Positive(t, custom.sets["flag/TestTrueOn"])
+	assert.True(t, custom.Contains("flag/TestTrueOn"))
+}
+
+func TestCfClient_BoolVariationDetail(t *testing.T) {
+	authSuccessResponse := AuthResponse(200, ValidAuthToken)
+	registerResponders(authSuccessResponse, TargetSegmentsResponse, FeatureConfigsResponse)
+	client, target, err := MakeNewSynchronousClientAndTarget(ValidSDKKey)
+	if err != nil {
+		t.Error(err)
+	}
+
+	tests := []struct {
+		name          string
+		key           string
+		want          bool
+		wantReason    evaluation.ReasonKind
+		wantErrorKind evaluation.ErrorKind
+		wantErr       bool
+	}{
+		{"Test Invalid Flag Name returns flag not found reason", "MadeUpIDontExist", true, evaluation.ReasonError, evaluation.ErrorKindFlagNotFound, true},
+		{"Test Flag when On returns default serve reason", "TestTrueOn", true, evaluation.ReasonDefaultServe, evaluation.ErrorKindNone, false},
+		{"Test Flag when Off returns flag off reason", "TestTrueOff", false, evaluation.ReasonFlagOff, evaluation.ErrorKindNone, false},
+		{"Test Flag when Pre-Req is False returns prerequisite failed reason", "TestTrueOnWithPreReqFalse", false, evaluation.ReasonPrerequisiteFailed, evaluation.ErrorKindNone, false},
+	}
+	for _, tt := range tests {
+		test := tt
+		t.Run(test.name, func(t *testing.T) {
+			detail, err := client.BoolVariationDetail(test.key, target, true)
+			if (err != nil) != test.wantErr {
+				t.Errorf("BoolVariationDetail() error = %v, wantErr %v", err, test.wantErr)
+				return
+			}
+			assert.Equal(t, test.want, detail.Value)
+			assert.Equal(t, test.wantReason, detail.Reason.Kind)
+			assert.Equal(t, test.wantErrorKind, detail.Reason.ErrorKind)
+		})
+	}
+
+	t.Run("Uninitialized client returns client not ready reason", func(t *testing.T) {
+		uninitialized, _ := newClient(http.DefaultClient, EmptySDKKey)
+		detail, err := uninitialized.BoolVariationDetail("TestTrueOn", target, true)
+		assert.True(t, errors.Is(err, DefaultVariationReturnedError))
+		assert.Equal(t, true, detail.Value)
+		assert.Equal(t, evaluation.ErrorKindClientNotReady, detail.Reason.ErrorKind)
+	})
+}
+
 func TestCfClient_DefaultVariationReturned(t *testing.T) {
 
 	tests := []struct {
@@ -731,6 +1295,35 @@ var FeatureConfigsResponse = func(req *http.Request) (*http.Response, error) {
 	return httpmock.NewJsonResponse(200, FeatureConfigResponse)
 }
 
+func TestCfClient_DoesNotModifyHTTPClient(t *testing.T) {
+	var sdkInfo []string
+	var mu sync.Mutex
+	registerResponders(AuthResponse(200, ValidAuthToken), TargetSegmentsResponse,
+		func(req *http.Request) (*http.Response, error) {
+			mu.Lock()
+			sdkInfo = append(sdkInfo, req.Header.Get("Harness-SDK-Info"))
+			mu.Unlock()
+			return FeatureConfigsResponse(req)
+		})
+
+	httpClient := &http.Client{}
+	client, err := newClient(httpClient, ValidSDKKey, WithWaitForInitialized(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	t.Log("Then the http client passed to WithHTTPClient isn't changed")
+	assert.Nil(t, httpClient.Transport)
+
+	t.Log("And the SDK's requests still carry its headers")
+	mu.Lock()
+	defer mu.Unlock()
+	if assert.NotEmpty(t, sdkInfo) {
+		assert.Contains(t, sdkInfo[0], "Go")
+	}
+}
+
 func TestCfClient_Close(t *testing.T) {
 	registerResponders(AuthResponse(200, ValidAuthToken), TargetSegmentsResponse, FeatureConfigsResponse)
 	client, err := newClient(&http.Client{}, ValidSDKKey, WithWaitForInitialized(true))
@@ -745,6 +1338,132 @@ func TestCfClient_Close(t *testing.T) {
 	assert.NotNil(t, client.Close())
 }
 
+func TestCfClient_CloseWaitsForStream(t *testing.T) {
+	registerResponders(AuthResponse(200, ValidAuthToken), TargetSegmentsResponse, FeatureConfigsResponse)
+
+	// the stream request blocks until the client cancels it
+	requested := make(chan struct{})
+	var once sync.Once
+	httpmock.RegisterResponder("GET", "http://localhost/api/1.0/stream", func(req *http.Request) (*http.Response, error) {
+		once.Do(func() { close(requested) })
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	})
+
+	client, err := newClient(&http.Client{}, ValidSDKKey, WithWaitForInitialized(true), WithStreamEnabled(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-requested:
+	case <-time.After(time.Second):
+		t.Fatal("the stream wasn't requested")
+	}
+
+	t.Log("When I close the client the stream goroutines have exited by the time it returns")
+	assert.Nil(t, client.Close())
+	assert.Eventually(t, func() bool {
+		buf := make([]byte, 1<<20)
+		return !bytes.Contains(buf[:runtime.Stack(buf, true)], []byte("ff-golang-server-sdk/stream.(*SSEClient)"))
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestStorePersister_PersistsPendingChangesWhenStopped(t *testing.T) {
+	store := storage.NewFileStore("test", t.TempDir(), logger.NewNoOpLogger())
+	persister := newStorePersister(store, ValidSDKKey, logger.NewNoOpLogger())
+	lru, err := cache.NewLruCache(100, logger.NewNoOpLogger())
+	assert.Nil(t, err)
+	persister.repository = repository.NewWithStorageAndCallback(lru, nil, persister)
+	persister.setEnvironment("env")
+
+	persister.repository.SetFlag(test_helpers.MakeBoolFeatureConfig("TestTrueOn", "true", "false", "on", nil), false)
+
+	t.Log("When the persister is stopped with a change pending the change is persisted before it returns")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	persister.run(ctx)
+
+	persistence := cache.NewPersistence(store, nil, logger.NewNoOpLogger(), cache.WithSDKKey(ValidSDKKey))
+	snapshot, err := persistence.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, "env", snapshot.Environment)
+	if assert.Len(t, snapshot.Flags, 1) {
+		assert.Equal(t, "TestTrueOn", snapshot.Flags[0].Feature)
+	}
+}
+
+func TestCfClient_CloseWithContext(t *testing.T) {
+	registerResponders(AuthResponse(200, ValidAuthToken), TargetSegmentsResponse, FeatureConfigsResponse)
+
+	var posted []metricsclient.PostMetricsJSONRequestBody
+	var mu sync.Mutex
+	httpmock.RegisterResponder("POST", "http://localhost/api/1.0/metrics/7ed1025d-a9b1-4129-a88f-e27ef360982d",
+		func(req *http.Request) (*http.Response, error) {
+			var body metricsclient.PostMetricsJSONRequestBody
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				return nil, err
+			}
+			mu.Lock()
+			posted = append(posted, body)
+			mu.Unlock()
+			return httpmock.NewStringResponse(200, ""), nil
+		})
+
+	client, err := newClient(&http.Client{}, ValidSDKKey, WithWaitForInitialized(true), WithEventsURL(URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err := client.BoolVariation("TestTrueOn", target(), false)
+		assert.Nil(t, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, client.CloseWithContext(ctx))
+
+	t.Log("Then the evaluations that hadn't been sent are posted before it returns")
+	mu.Lock()
+	defer mu.Unlock()
+	if assert.Len(t, posted, 1) && assert.NotNil(t, posted[0].MetricsData) && assert.Len(t, *posted[0].MetricsData, 1) {
+		assert.Equal(t, 3, (*posted[0].MetricsData)[0].Count)
+	}
+
+	assert.NotNil(t, client.CloseWithContext(ctx))
+}
+
+func TestCfClient_ExportersWithAnalyticsDisabled(t *testing.T) {
+	registerResponders(AuthResponse(200, ValidAuthToken), TargetSegmentsResponse, FeatureConfigsResponse)
+
+	var posts atomic.Int32
+	httpmock.RegisterResponder("POST", "http://localhost/api/1.0/metrics/7ed1025d-a9b1-4129-a88f-e27ef360982d",
+		func(req *http.Request) (*http.Response, error) {
+			posts.Add(1)
+			return httpmock.NewStringResponse(200, ""), nil
+		})
+
+	var exported bytes.Buffer
+	client, err := newClient(&http.Client{}, ValidSDKKey, WithWaitForInitialized(true), WithEventsURL(URL),
+		WithAnalyticsEnabled(false), WithAnalyticsExporter(analyticsservice.NewJSONExporter(&exported)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err := client.BoolVariation("TestTrueOn", target(), false)
+		assert.Nil(t, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, client.CloseWithContext(ctx))
+
+	t.Log("Then the exporter receives the evaluations and nothing is posted to Harness")
+	assert.Contains(t, exported.String(), `"count":3`)
+	assert.Equal(t, int32(0), posts.Load())
+}
+
 // getInstantRetryStrategy returns a strategy that retries every millisecond for testing purposes
 func getInstantRetryStrategy() *backoff.ExponentialBackOff {
 	exponentialBackOff := backoff.NewExponentialBackOff()
@@ -3,10 +3,10 @@ package client
 import (
 	"fmt"
 	"net/http"
-	"os"
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
+	"github.com/harness/ff-golang-server-sdk/analyticsservice"
 	"github.com/harness/ff-golang-server-sdk/cache"
 	"github.com/harness/ff-golang-server-sdk/evaluation"
 	"github.com/harness/ff-golang-server-sdk/logger"
@@ -40,6 +40,16 @@ type config struct {
 	apiConfig                *apiConfiguration
 	seenTargetsMaxSize       int
 	seenTargetsClearInterval time.Duration
+	analyticsInterval        time.Duration
+	maxEvaluationMetrics     int
+	maxTargetMetrics         int
+	analyticsSpool           analyticsservice.Spool
+	analyticsSpoolMaxAge     time.Duration
+	analyticsExporters       []analyticsservice.Exporter
+	customOperators          map[string]evaluation.CustomOperator
+	offlinePath              string
+	offlineWatchInterval     time.Duration
+	sharedCacheReader        bool
 }
 
 type apiConfiguration struct {
@@ -52,10 +62,6 @@ func (a *apiConfiguration) GetSegmentRulesV2QueryParam() *rest.SegmentRulesV2Que
 
 func newDefaultConfig(log logger.Logger) *config {
 	defaultCache, _ := cache.NewLruCache(10000, log) // size of cache
-	var defaultStore storage.Storage
-	if _, present := os.LookupEnv("DISABLE_LOCAL_CACHE"); !present {
-		defaultStore = storage.NewFileStore("defaultProject", storage.GetHarnessDir(log), log)
-	}
 
 	// Authentication uses a default http client + timeout as we have our own custom retry logic for authentication.
 	const requestTimeout = time.Second * 30
@@ -93,7 +99,6 @@ func newDefaultConfig(log logger.Logger) *config {
 		eventsURL:                "https://events.ff.harness.io/api/1.0",
 		pullInterval:             60,
 		Cache:                    defaultCache,
-		Store:                    defaultStore,
 		Logger:                   log,
 		authHttpClient:           authHttpClient,
 		httpClient:               requestHttpClient.StandardClient(),
@@ -108,6 +113,9 @@ func newDefaultConfig(log logger.Logger) *config {
 		apiConfig:                apiConfig,
 		seenTargetsMaxSize:       500000,
 		seenTargetsClearInterval: 24 * time.Hour,
+		analyticsInterval:        time.Minute,
+		maxEvaluationMetrics:     10000,
+		maxTargetMetrics:         100000,
 	}
 }
 
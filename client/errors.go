@@ -9,6 +9,8 @@ var (
 	EmptySDKKeyError              = errors.New("default variation was returned")
 	DefaultVariationReturnedError = errors.New("default variation was returned")
 	FetchFlagsError               = errors.New("fetching flags failed")
+	OfflineSnapshotError          = errors.New("loading offline snapshot failed")
+	SharedCacheError              = errors.New("reading shared cache failed")
 )
 
 type NonRetryableAuthError struct {
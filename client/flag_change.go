@@ -0,0 +1,306 @@
+package client
+
+import (
+	"hash/fnv"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+	"golang.org/x/exp/slices"
+
+	"github.com/harness/ff-golang-server-sdk/evaluation"
+	"github.com/harness/ff-golang-server-sdk/logger"
+	"github.com/harness/ff-golang-server-sdk/pkg/repository"
+	"github.com/harness/ff-golang-server-sdk/rest"
+)
+
+// FlagChangeEvent is passed to OnFlagChange listeners when a stored flag changes, either because the
+// flag itself was updated or deleted, or because a target segment it uses was updated or deleted
+type FlagChangeEvent struct {
+	// Flag is the identifier of the flag that changed
+	Flag string
+	// Deleted is true if the flag has been removed
+	Deleted bool
+}
+
+// flagValueListener tracks the last variation served to a target so the listener only fires
+// when the value actually changes
+type flagValueListener struct {
+	flag   string
+	target *evaluation.Target
+	last   rest.Variation
+	fn     func(old, new rest.Variation)
+}
+
+// flagChangeNotifier implements repository.Callback. Polling re-stores every flag and segment, so a
+// fingerprint of each is tracked to only notify listeners when something actually changed. The content
+// is fingerprinted rather than relying on the version, as offline snapshots are often edited by hand.
+// Until the initial flags and segments have been loaded the fingerprints are recorded without notifying
+// listeners, so a listener registered before the first poll isn't called for every flag.
+type flagChangeNotifier struct {
+	repository repository.Repository
+	// evaluator has no post evaluation callback so that listener evaluations aren't counted in analytics
+	evaluator *evaluation.Evaluator
+	logger    logger.Logger
+
+	mu                  sync.Mutex
+	seeded              bool
+	flagFingerprints    map[string]uint64
+	segmentFingerprints map[string]uint64
+	listeners           []func(FlagChangeEvent)
+	valueListeners      []*flagValueListener
+}
+
+var _ repository.Callback = &flagChangeNotifier{}
+
+func newFlagChangeNotifier(logger logger.Logger) *flagChangeNotifier {
+	return &flagChangeNotifier{
+		logger:              logger,
+		flagFingerprints:    map[string]uint64{},
+		segmentFingerprints: map[string]uint64{},
+	}
+}
+
+func (n *flagChangeNotifier) addListener(fn func(FlagChangeEvent)) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.listeners = append(n.listeners, fn)
+}
+
+func (n *flagChangeNotifier) addValueListener(flag string, target *evaluation.Target, fn func(old, new rest.Variation)) {
+	listener := &flagValueListener{
+		flag:   flag,
+		target: target,
+		last:   n.evaluate(flag, target),
+		fn:     fn,
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.valueListeners = append(n.valueListeners, listener)
+}
+
+// seed is called once the initial flags and segments have been loaded. Changes are notified from then on,
+// and value listeners registered earlier start from the loaded value rather than the default.
+func (n *flagChangeNotifier) seed() {
+	n.mu.Lock()
+	if n.seeded {
+		n.mu.Unlock()
+		return
+	}
+	n.seeded = true
+	valueListeners := append([]*flagValueListener{}, n.valueListeners...)
+	n.mu.Unlock()
+
+	for _, listener := range valueListeners {
+		latest := n.evaluate(listener.flag, listener.target)
+		n.mu.Lock()
+		listener.last = latest
+		n.mu.Unlock()
+	}
+}
+
+func (n *flagChangeNotifier) evaluate(flag string, target *evaluation.Target) rest.Variation {
+	if n.evaluator == nil {
+		return rest.Variation{}
+	}
+	fv, _, err := n.evaluator.EvaluateDetail(flag, target)
+	if err != nil {
+		return rest.Variation{}
+	}
+	return fv.Variation
+}
+
+// OnFlagStored notifies listeners if the flag changed
+func (n *flagChangeNotifier) OnFlagStored(identifier string) {
+	n.notify(n.storedFlags(identifier))
+}
+
+// OnFlagsStored notifies listeners of every flag that changed
+func (n *flagChangeNotifier) OnFlagsStored(envID string) {
+	flags, err := n.repository.GetFlags()
+	if err != nil {
+		n.logger.Warnf("unable to check flags for changes in env=%s: %v", envID, err)
+		return
+	}
+	identifiers := make([]string, 0, len(flags))
+	for _, flag := range flags {
+		identifiers = append(identifiers, flag.Feature)
+	}
+	n.notify(n.storedFlags(identifiers...))
+}
+
+// OnFlagDeleted notifies listeners that the flag was removed
+func (n *flagChangeNotifier) OnFlagDeleted(identifier string) {
+	n.notify(n.deletedFlag(identifier))
+}
+
+// OnFlagsDeleted notifies listeners that the flag was removed, unless OnFlagDeleted already did
+func (n *flagChangeNotifier) OnFlagsDeleted(envID string, identifier string) {
+	n.notify(n.deletedFlag(identifier))
+}
+
+// OnSegmentStored notifies listeners of the flags using the segment if it changed
+func (n *flagChangeNotifier) OnSegmentStored(identifier string) {
+	segment, err := n.repository.GetSegment(identifier)
+	if err != nil {
+		return
+	}
+	n.mu.Lock()
+	sum := fingerprint(segment)
+	if old, ok := n.segmentFingerprints[identifier]; ok && old == sum {
+		n.mu.Unlock()
+		return
+	}
+	n.segmentFingerprints[identifier] = sum
+	seeded := n.seeded
+	n.mu.Unlock()
+
+	if seeded {
+		n.notify(n.flagsUsingSegment(identifier))
+	}
+}
+
+// OnSegmentsStored is a no-op, segments are always stored individually as well so changes are
+// picked up by OnSegmentStored
+func (n *flagChangeNotifier) OnSegmentsStored(envID string) {}
+
+// OnSegmentDeleted notifies listeners of the flags using the segment
+func (n *flagChangeNotifier) OnSegmentDeleted(identifier string) {
+	n.mu.Lock()
+	_, ok := n.segmentFingerprints[identifier]
+	delete(n.segmentFingerprints, identifier)
+	seeded := n.seeded
+	n.mu.Unlock()
+
+	if ok && seeded {
+		n.notify(n.flagsUsingSegment(identifier))
+	}
+}
+
+// OnSegmentsDeleted notifies listeners of the flags using the segment, unless OnSegmentDeleted already did
+func (n *flagChangeNotifier) OnSegmentsDeleted(envID string, identifier string) {
+	n.OnSegmentDeleted(identifier)
+}
+
+// storedFlags returns an event for each flag that is new or differs from the one last seen
+func (n *flagChangeNotifier) storedFlags(identifiers ...string) []FlagChangeEvent {
+	var events []FlagChangeEvent
+	for _, identifier := range identifiers {
+		flag, err := n.repository.GetFlag(identifier)
+		if err != nil {
+			continue
+		}
+		sum := fingerprint(flag)
+
+		n.mu.Lock()
+		old, ok := n.flagFingerprints[identifier]
+		n.flagFingerprints[identifier] = sum
+		seeded := n.seeded
+		n.mu.Unlock()
+
+		if seeded && (!ok || old != sum) {
+			events = append(events, FlagChangeEvent{Flag: identifier})
+		}
+	}
+	return events
+}
+
+func (n *flagChangeNotifier) deletedFlag(identifier string) []FlagChangeEvent {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if _, ok := n.flagFingerprints[identifier]; !ok {
+		return nil
+	}
+	delete(n.flagFingerprints, identifier)
+	if !n.seeded {
+		return nil
+	}
+	return []FlagChangeEvent{{Flag: identifier, Deleted: true}}
+}
+
+func (n *flagChangeNotifier) flagsUsingSegment(segment string) []FlagChangeEvent {
+	flags, err := n.repository.GetFlags()
+	if err != nil {
+		return nil
+	}
+	var events []FlagChangeEvent
+	for _, flag := range flags {
+		if flagUsesSegment(flag, segment) {
+			events = append(events, FlagChangeEvent{Flag: flag.Feature})
+		}
+	}
+	return events
+}
+
+// flagUsesSegment checks if the flag targets the segment directly or through a segmentMatch rule
+func flagUsesSegment(flag rest.FeatureConfig, segment string) bool {
+	if flag.VariationToTargetMap != nil {
+		for _, vm := range *flag.VariationToTargetMap {
+			if vm.TargetSegments != nil && slices.Contains(*vm.TargetSegments, segment) {
+				return true
+			}
+		}
+	}
+	if flag.Rules != nil {
+		for _, rule := range *flag.Rules {
+			for _, clause := range rule.Clauses {
+				if clause.Op == "segmentMatch" && slices.Contains(clause.Values, segment) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// notify calls the change listeners with the events, then re-evaluates value listeners. Any flag
+// change can affect a value listener through prerequisites, so they're all re-evaluated.
+func (n *flagChangeNotifier) notify(events []FlagChangeEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	n.mu.Lock()
+	listeners := append([]func(FlagChangeEvent){}, n.listeners...)
+	valueListeners := append([]*flagValueListener{}, n.valueListeners...)
+	n.mu.Unlock()
+
+	for _, event := range events {
+		for _, fn := range listeners {
+			n.call(func() { fn(event) })
+		}
+	}
+
+	for _, listener := range valueListeners {
+		latest := n.evaluate(listener.flag, listener.target)
+
+		n.mu.Lock()
+		old := listener.last
+		listener.last = latest
+		n.mu.Unlock()
+
+		if old.Identifier != latest.Identifier || old.Value != latest.Value {
+			n.call(func() { listener.fn(old, latest) })
+		}
+	}
+}
+
+// call runs a listener, recovering from panics so a faulty listener can't stop flag updates
+func (n *flagChangeNotifier) call(fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			n.logger.Errorf("flag change listener panicked: %v", r)
+		}
+	}()
+	fn()
+}
+
+// fingerprint hashes the JSON encoding of a flag or segment
+func fingerprint(v interface{}) uint64 {
+	b, err := jsoniter.Marshal(v)
+	if err != nil {
+		return 0
+	}
+	h := fnv.New64a()
+	_, _ = h.Write(b)
+	return h.Sum64()
+}
@@ -0,0 +1,236 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"gopkg.in/yaml.v3"
+
+	"github.com/harness/ff-golang-server-sdk/evaluation"
+	"github.com/harness/ff-golang-server-sdk/pkg/repository"
+	"github.com/harness/ff-golang-server-sdk/rest"
+)
+
+// offlineEnvironment is used as the environment ID when serving flags from a snapshot file
+const offlineEnvironment = "offline"
+
+// offlineSnapshot is the content of a flag snapshot file, it uses the same layout as the
+// ff-test-cases files so they can be used as snapshots as well
+type offlineSnapshot struct {
+	Flags    []rest.FeatureConfig `json:"flags"`
+	Segments []rest.Segment       `json:"segments"`
+}
+
+// isSnapshotFile checks the file has a supported extension, hidden files are skipped so that
+// the ..data directories Kubernetes uses to update ConfigMap volumes aren't read twice
+func isSnapshotFile(name string) bool {
+	if strings.HasPrefix(name, ".") {
+		return false
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json", ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+// readSnapshotFiles returns the content of the snapshot file at path, or of every snapshot file in
+// path if it is a directory
+func readSnapshotFiles(path string) (map[string][]byte, error) {
+	path = filepath.Clean(path)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", OfflineSnapshotError, err)
+	}
+
+	if !info.IsDir() {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", OfflineSnapshotError, err)
+		}
+		return map[string][]byte{path: content}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", OfflineSnapshotError, err)
+	}
+	files := map[string][]byte{}
+	for _, entry := range entries {
+		if entry.IsDir() || !isSnapshotFile(entry.Name()) {
+			continue
+		}
+		name := filepath.Join(path, entry.Name())
+		content, err := os.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", OfflineSnapshotError, err)
+		}
+		files[name] = content
+	}
+	return files, nil
+}
+
+// snapshotChecksum fingerprints the snapshot files so unchanged files aren't reloaded
+func snapshotChecksum(files map[string][]byte) uint64 {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := fnv.New64a()
+	for _, name := range names {
+		_, _ = h.Write([]byte(name))
+		_, _ = h.Write([]byte{0})
+		_, _ = h.Write(files[name])
+		_, _ = h.Write([]byte{0})
+	}
+	return h.Sum64()
+}
+
+// parseSnapshotFiles merges the flags and segments of every file, files are read in name order
+func parseSnapshotFiles(files map[string][]byte) (offlineSnapshot, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var merged offlineSnapshot
+	for _, name := range names {
+		snapshot, err := parseSnapshot(name, files[name])
+		if err != nil {
+			return offlineSnapshot{}, err
+		}
+		merged.Flags = append(merged.Flags, snapshot.Flags...)
+		merged.Segments = append(merged.Segments, snapshot.Segments...)
+	}
+	return merged, nil
+}
+
+// parseSnapshot decodes a snapshot file, files with a .yaml or .yml extension are read as YAML and
+// anything else as JSON
+func parseSnapshot(name string, content []byte) (offlineSnapshot, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml":
+		// the rest types only have json tags, so convert the YAML to JSON rather than decoding it directly
+		var doc interface{}
+		if err := yaml.Unmarshal(content, &doc); err != nil {
+			return offlineSnapshot{}, fmt.Errorf("%w: %s: %v", OfflineSnapshotError, name, err)
+		}
+		var err error
+		content, err = jsoniter.Marshal(doc)
+		if err != nil {
+			return offlineSnapshot{}, fmt.Errorf("%w: %s: %v", OfflineSnapshotError, name, err)
+		}
+	}
+
+	var snapshot offlineSnapshot
+	if err := jsoniter.Unmarshal(content, &snapshot); err != nil {
+		return offlineSnapshot{}, fmt.Errorf("%w: %s: %v", OfflineSnapshotError, name, err)
+	}
+	return snapshot, nil
+}
+
+// offlineSource serves the repository from snapshot files, optionally watching them for changes
+type offlineSource struct {
+	client   *CfClient
+	path     string
+	checksum uint64
+}
+
+// load reads the snapshot files and applies them to the repository if they changed. The files are
+// fully parsed and validated before anything is stored so a bad edit leaves the previous flags in place.
+func (s *offlineSource) load() (bool, error) {
+	files, err := readSnapshotFiles(s.path)
+	if err != nil {
+		return false, err
+	}
+	checksum := snapshotChecksum(files)
+	if checksum == s.checksum {
+		return false, nil
+	}
+	snapshot, err := parseSnapshotFiles(files)
+	if err != nil {
+		return false, err
+	}
+	if err := validateSnapshot(snapshot); err != nil {
+		return false, err
+	}
+
+	if err := s.apply(snapshot); err != nil {
+		return false, err
+	}
+	s.checksum = checksum
+	return true, nil
+}
+
+// validateSnapshot checks the prerequisites of the snapshot's flags only refer to each other without
+// forming a cycle or a chain that's too deep
+func validateSnapshot(snapshot offlineSnapshot) error {
+	errs := evaluation.ValidatePrerequisites(nil, snapshot.Flags...)
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: invalid flag prerequisites: %v", OfflineSnapshotError, errors.Join(errs...))
+}
+
+// apply replaces the flags and segments held by the repository with the snapshot in one step, so an
+// evaluation made during a reload sees either the previous snapshot or the new one. Flag change listeners
+// are notified of the flags and segments that were added, changed or removed.
+func (s *offlineSource) apply(snapshot offlineSnapshot) error {
+	replacer, ok := s.client.repository.(repository.Replacer)
+	if !ok {
+		return fmt.Errorf("%w: the repository can't replace its flags", OfflineSnapshotError)
+	}
+	replacer.ReplaceAll(s.client.environmentID, snapshot.Flags, snapshot.Segments)
+	s.client.config.Logger.Infof("Loaded %d flags and %d segments from offline snapshot %s", len(snapshot.Flags), len(snapshot.Segments), s.path)
+	return nil
+}
+
+// watch reloads the snapshot every interval until ctx is cancelled
+func (s *offlineSource) watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.load(); err != nil {
+				s.client.config.Logger.Errorf("Failed to reload offline snapshot, keeping previous flags: %v", err)
+			}
+		}
+	}
+}
+
+// loadOffline populates the repository from the snapshot files and marks the client as initialized
+// without contacting the Feature Flag service. If a watch interval is configured the files are
+// reloaded whenever they change.
+func (c *CfClient) loadOffline(path string) error {
+	c.environmentID = offlineEnvironment
+	source := &offlineSource{client: c, path: path}
+	if _, err := source.load(); err != nil {
+		return err
+	}
+
+	if c.config.offlineWatchInterval > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			<-c.stop
+			cancel()
+		}()
+		c.spawn(func() { source.watch(ctx, c.config.offlineWatchInterval) })
+	}
+
+	c.markInitialized()
+	return nil
+}
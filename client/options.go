@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
+	"github.com/harness/ff-golang-server-sdk/analyticsservice"
 	"github.com/harness/ff-golang-server-sdk/cache"
 	"github.com/harness/ff-golang-server-sdk/evaluation"
 	"github.com/harness/ff-golang-server-sdk/logger"
@@ -163,3 +164,87 @@ func WithSeenTargetsClearInterval(interval time.Duration) ConfigOption {
 		config.seenTargetsClearInterval = interval
 	}
 }
+
+// WithAnalyticsInterval sets how often evaluation metrics are sent to the Feature Flag service. By default, the
+// interval is one minute, it can't be less than a minute or more than an hour.
+func WithAnalyticsInterval(interval time.Duration) ConfigOption {
+	return func(config *config) {
+		config.analyticsInterval = interval
+	}
+}
+
+// WithMaxEvaluationMetrics sets the most evaluation metrics sent in a single request, by default 10000. Metrics
+// beyond the limit aren't dropped, they're sent in further requests.
+func WithMaxEvaluationMetrics(max int) ConfigOption {
+	return func(config *config) {
+		config.maxEvaluationMetrics = max
+	}
+}
+
+// WithMaxTargetMetrics sets the most targets sent in a single metrics request, by default 100000. Targets beyond
+// the limit aren't dropped, they're sent in further requests.
+func WithMaxTargetMetrics(max int) ConfigOption {
+	return func(config *config) {
+		config.maxTargetMetrics = max
+	}
+}
+
+// WithAnalyticsSpool keeps metrics that fail to send in spool and retries them at each analytics interval,
+// waiting longer after each failure, until they're older than maxAge (24 hours if it's zero). Use
+// analyticsservice.NewMemorySpool, or analyticsservice.NewDiskSpool to also retry them after a restart.
+func WithAnalyticsSpool(spool analyticsservice.Spool, maxAge time.Duration) ConfigOption {
+	return func(config *config) {
+		config.analyticsSpool = spool
+		config.analyticsSpoolMaxAge = maxAge
+	}
+}
+
+// WithAnalyticsExporter sends the metrics for each analytics interval to exporter as well as the Feature Flag
+// service, e.g. analyticsservice.NewJSONExporter(os.Stdout). It can be passed several times to register several
+// exporters. Exporters are used even if analytics are disabled with WithAnalyticsEnabled(false).
+func WithAnalyticsExporter(exporter analyticsservice.Exporter) ConfigOption {
+	return func(config *config) {
+		config.analyticsExporters = append(config.analyticsExporters, exporter)
+	}
+}
+
+// WithCustomOperator registers a clause operator for rules that use an Op the SDK doesn't implement,
+// e.g. CIDR ranges or geo-fencing. fn receives the raw target attribute value and the clause values,
+// and is only consulted for operators the SDK doesn't recognise.
+func WithCustomOperator(name string, fn func(attrValue interface{}, values []string) bool) ConfigOption {
+	return func(config *config) {
+		if config.customOperators == nil {
+			config.customOperators = map[string]evaluation.CustomOperator{}
+		}
+		config.customOperators[name] = fn
+	}
+}
+
+// WithOfflineMode serves flags and segments from a local JSON or YAML snapshot file instead of the Feature Flag
+// service. The client doesn't authenticate, poll, stream or send analytics, and an SDK key isn't required.
+// The file holds "flags" and "segments" lists in the same format as the ff-test-cases files. path may also be
+// a directory, in which case every .json, .yaml and .yml file in it is loaded.
+func WithOfflineMode(path string) ConfigOption {
+	return func(config *config) {
+		config.offlinePath = path
+	}
+}
+
+// WithOfflineWatch checks the WithOfflineMode snapshot for changes every interval, and reloads it when it
+// changes so flag edits apply without a restart e.g. when flags are mounted from a Kubernetes ConfigMap.
+// Flags and segments removed from the snapshot are deleted, and flag change listeners are notified.
+func WithOfflineWatch(interval time.Duration) ConfigOption {
+	return func(config *config) {
+		config.offlineWatchInterval = interval
+	}
+}
+
+// WithSharedCacheReader serves the flags that another instance writes to the cache passed with WithCache,
+// which must be a repository.RefreshableCache such as rediscache.RedisCache. The client doesn't authenticate,
+// poll, stream or send analytics, and an SDK key isn't required. Instead the cache's in-process copy is
+// refreshed every pull interval, so evaluations never wait for the shared cache.
+func WithSharedCacheReader() ConfigOption {
+	return func(config *config) {
+		config.sharedCacheReader = true
+	}
+}
@@ -0,0 +1,60 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/harness/ff-golang-server-sdk/pkg/repository"
+)
+
+// sharedCacheRefreshTimeout is how long a refresh of the shared cache may take
+const sharedCacheRefreshTimeout = time.Minute
+
+// loadSharedCache refreshes the shared cache passed with WithCache and marks the client as initialized
+// without contacting the Feature Flag service. The cache is then refreshed every pull interval, in place
+// of polling.
+func (c *CfClient) loadSharedCache() error {
+	shared, ok := c.config.Cache.(repository.RefreshableCache)
+	if !ok {
+		return fmt.Errorf("%w: the cache doesn't implement repository.RefreshableCache", SharedCacheError)
+	}
+	if err := c.refreshSharedCache(context.Background(), shared); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-c.stop
+		cancel()
+	}()
+	c.spawn(func() { c.sharedCacheRefreshJob(ctx, shared) })
+
+	c.markInitialized()
+	return nil
+}
+
+func (c *CfClient) refreshSharedCache(ctx context.Context, shared repository.RefreshableCache) error {
+	ctx, cancel := context.WithTimeout(ctx, sharedCacheRefreshTimeout)
+	defer cancel()
+	if err := shared.Refresh(ctx); err != nil {
+		return fmt.Errorf("%w: %v", SharedCacheError, err)
+	}
+	return nil
+}
+
+// sharedCacheRefreshJob refreshes the shared cache every pull interval until ctx is cancelled
+func (c *CfClient) sharedCacheRefreshJob(ctx context.Context, shared repository.RefreshableCache) {
+	ticker := c.makeTicker(c.config.pullInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.refreshSharedCache(ctx, shared); err != nil {
+				c.config.Logger.Warnf("Failed to refresh the shared cache, keeping the previous flags: %v", err)
+			}
+		}
+	}
+}
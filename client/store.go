@@ -0,0 +1,255 @@
+package client
+
+import (
+	"context"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/harness/ff-golang-server-sdk/cache"
+	"github.com/harness/ff-golang-server-sdk/logger"
+	"github.com/harness/ff-golang-server-sdk/pkg/repository"
+	"github.com/harness/ff-golang-server-sdk/storage"
+)
+
+// defaultStore creates the file store in ~/harness that's used if WithStore isn't passed. The file is named
+// after a hash of the SDK key, so clients with different SDK keys on the same host don't share a file. It
+// returns nil if the DISABLE_LOCAL_CACHE environment variable is set.
+func defaultStore(sdkKey string, log logger.Logger) storage.Storage {
+	if _, present := os.LookupEnv("DISABLE_LOCAL_CACHE"); present {
+		return nil
+	}
+	return storage.NewFileStore("sdk-"+cache.HashSDKKey(sdkKey)[:16], storage.GetHarnessDir(log), log)
+}
+
+// storePersister implements repository.Callback and saves the last-known-good flags and segments to
+// the store with cache.Persistence whenever the repository changes, so they can be served after a
+// restart even if the Feature Flag service can't be reached.
+type storePersister struct {
+	persistence cache.Persistence
+	repository  repository.Repository
+	logger      logger.Logger
+
+	mu       sync.Mutex
+	envID    string
+	segments map[string]struct{}
+	// changed is signalled by the callbacks, it's buffered so updates that happen while the store is
+	// being written are coalesced into a single write
+	changed chan struct{}
+}
+
+var _ repository.Callback = &storePersister{}
+
+func newStorePersister(store storage.Storage, sdkKey string, logger logger.Logger) *storePersister {
+	return &storePersister{
+		persistence: cache.NewPersistence(store, nil, logger, cache.WithSDKKey(sdkKey)),
+		logger:      logger,
+		segments:    map[string]struct{}{},
+		changed:     make(chan struct{}, 1),
+	}
+}
+
+func (p *storePersister) signal() {
+	select {
+	case p.changed <- struct{}{}:
+	default:
+	}
+}
+
+// drain discards pending changes, it's used after loading from the store so the same data isn't
+// written straight back
+func (p *storePersister) drain() {
+	select {
+	case <-p.changed:
+	default:
+	}
+}
+
+func (p *storePersister) setEnvironment(envID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.envID = envID
+}
+
+// OnFlagStored persists the flags
+func (p *storePersister) OnFlagStored(identifier string) {
+	p.signal()
+}
+
+// OnFlagsStored persists the flags
+func (p *storePersister) OnFlagsStored(envID string) {
+	p.setEnvironment(envID)
+	p.signal()
+}
+
+// OnFlagsDeleted persists the flags
+func (p *storePersister) OnFlagsDeleted(envID string, identifier string) {
+	p.signal()
+}
+
+// OnFlagDeleted persists the flags
+func (p *storePersister) OnFlagDeleted(identifier string) {
+	p.signal()
+}
+
+// OnSegmentStored persists the segments
+func (p *storePersister) OnSegmentStored(identifier string) {
+	p.mu.Lock()
+	p.segments[identifier] = struct{}{}
+	p.mu.Unlock()
+	p.signal()
+}
+
+// OnSegmentsStored persists the segments
+func (p *storePersister) OnSegmentsStored(envID string) {
+	p.setEnvironment(envID)
+	p.signal()
+}
+
+// OnSegmentDeleted persists the segments
+func (p *storePersister) OnSegmentDeleted(identifier string) {
+	p.mu.Lock()
+	delete(p.segments, identifier)
+	p.mu.Unlock()
+	p.signal()
+}
+
+// OnSegmentsDeleted persists the segments
+func (p *storePersister) OnSegmentsDeleted(envID string, identifier string) {
+	p.OnSegmentDeleted(identifier)
+}
+
+// run persists the repository whenever it changes until ctx is cancelled, a change that's still pending
+// when ctx is cancelled is persisted before it returns
+func (p *storePersister) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			select {
+			case <-p.changed:
+				p.persistOrWarn()
+			default:
+			}
+			return
+		case <-p.changed:
+			p.persistOrWarn()
+		}
+	}
+}
+
+func (p *storePersister) persistOrWarn() {
+	if err := p.persist(); err != nil {
+		p.logger.Warnf("Failed to persist flags to the store: %v", err)
+	}
+}
+
+// persist saves the flags and segments held by the repository to the store
+func (p *storePersister) persist() error {
+	flags, err := p.repository.GetFlags()
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	snapshot := cache.Snapshot{Environment: p.envID, Flags: flags}
+	identifiers := make([]string, 0, len(p.segments))
+	for identifier := range p.segments {
+		identifiers = append(identifiers, identifier)
+	}
+	p.mu.Unlock()
+	sort.Strings(identifiers)
+
+	for _, identifier := range identifiers {
+		segment, err := p.repository.GetSegment(identifier)
+		if err != nil {
+			continue
+		}
+		snapshot.Segments = append(snapshot.Segments, segment)
+	}
+	return p.persistence.Save(snapshot)
+}
+
+// loadStore populates the repository with the flags persisted by a previous run and marks the client
+// as initialized, so the stored flags are served until they can be retrieved from the Feature Flag
+// service. It returns false if the store was empty or couldn't be read.
+func (c *CfClient) loadStore() bool {
+	snapshot, err := c.persister.persistence.Load()
+	if err != nil {
+		if os.IsNotExist(err) {
+			c.config.Logger.Debugf("No flags have been persisted to the store yet")
+		} else {
+			c.config.Logger.Warnf("Unable to load flags from the store: %v", err)
+		}
+		return false
+	}
+	if len(snapshot.Flags) == 0 {
+		return false
+	}
+
+	envID := snapshot.Environment
+	c.environmentID = envID
+	c.repository.SetSegments(true, envID, snapshot.Segments...)
+	for _, segment := range snapshot.Segments {
+		c.repository.SetSegment(segment, true)
+	}
+	c.repository.SetFlags(true, envID, snapshot.Flags...)
+	for _, flag := range snapshot.Flags {
+		c.repository.SetFlag(flag, true)
+	}
+	c.persister.drain()
+	c.config.Logger.Infof("Loaded %d flags and %d segments from the store", len(snapshot.Flags), len(snapshot.Segments))
+
+	c.markInitialized()
+	return true
+}
+
+// repositoryCallbacks passes repository events to each callback in turn
+type repositoryCallbacks []repository.Callback
+
+func (r repositoryCallbacks) OnFlagStored(identifier string) {
+	for _, cb := range r {
+		cb.OnFlagStored(identifier)
+	}
+}
+
+func (r repositoryCallbacks) OnFlagsStored(envID string) {
+	for _, cb := range r {
+		cb.OnFlagsStored(envID)
+	}
+}
+
+func (r repositoryCallbacks) OnFlagsDeleted(envID string, identifier string) {
+	for _, cb := range r {
+		cb.OnFlagsDeleted(envID, identifier)
+	}
+}
+
+func (r repositoryCallbacks) OnFlagDeleted(identifier string) {
+	for _, cb := range r {
+		cb.OnFlagDeleted(identifier)
+	}
+}
+
+func (r repositoryCallbacks) OnSegmentStored(identifier string) {
+	for _, cb := range r {
+		cb.OnSegmentStored(identifier)
+	}
+}
+
+func (r repositoryCallbacks) OnSegmentsStored(envID string) {
+	for _, cb := range r {
+		cb.OnSegmentsStored(envID)
+	}
+}
+
+func (r repositoryCallbacks) OnSegmentDeleted(identifier string) {
+	for _, cb := range r {
+		cb.OnSegmentDeleted(identifier)
+	}
+}
+
+func (r repositoryCallbacks) OnSegmentsDeleted(envID string, identifier string) {
+	for _, cb := range r {
+		cb.OnSegmentsDeleted(envID, identifier)
+	}
+}
@@ -0,0 +1,102 @@
+package evaluation
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateOnlyLayout lets rules be written against whole days e.g. "created before 2025-01-01", which is
+// interpreted as midnight UTC
+const dateOnlyLayout = "2006-01-02"
+
+// toTime converts an attribute value into a time. time.Time values are used as is, numbers are
+// treated as milliseconds since the unix epoch, and strings may be RFC3339, a date or epoch milliseconds.
+func toTime(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case *time.Time:
+		if v == nil {
+			return time.Time{}, fmt.Errorf("%w: nil time", ErrInvalidTime)
+		}
+		return *v, nil
+	case string:
+		return parseTime(v)
+	case int:
+		return time.UnixMilli(int64(v)), nil
+	case int32:
+		return time.UnixMilli(int64(v)), nil
+	case int64:
+		return time.UnixMilli(v), nil
+	case uint:
+		return time.UnixMilli(int64(v)), nil
+	case uint32:
+		return time.UnixMilli(int64(v)), nil
+	case uint64:
+		return time.UnixMilli(int64(v)), nil
+	case float32:
+		return epochMillisFromFloat(float64(v))
+	case float64:
+		return epochMillisFromFloat(v)
+	default:
+		return time.Time{}, fmt.Errorf("%w: unsupported type %T", ErrInvalidTime, value)
+	}
+}
+
+// parseTime parses RFC3339, date only, or epoch millisecond strings
+func parseTime(value string) (time.Time, error) {
+	v := strings.TrimSpace(value)
+	if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(dateOnlyLayout, v); err == nil {
+		return t, nil
+	}
+	if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.UnixMilli(ms), nil
+	}
+	if ms, err := strconv.ParseFloat(v, 64); err == nil {
+		return epochMillisFromFloat(ms)
+	}
+	return time.Time{}, fmt.Errorf("%w: %q", ErrInvalidTime, value)
+}
+
+func epochMillisFromFloat(ms float64) (time.Time, error) {
+	if math.IsNaN(ms) || math.IsInf(ms, 0) {
+		return time.Time{}, fmt.Errorf("%w: %v", ErrInvalidTime, ms)
+	}
+	return time.UnixMilli(int64(ms)), nil
+}
+
+// evaluateTimeOperator checks the attribute time against the clause values. 'before' and 'after'
+// are exclusive, 'between' takes a start and end value and matches start <= attribute < end.
+func evaluateTimeOperator(op string, attr interface{}, values []string) (bool, error) {
+	attrTime, err := toTime(attr)
+	if err != nil {
+		return false, err
+	}
+	first, err := parseTime(values[0])
+	if err != nil {
+		return false, err
+	}
+
+	switch op {
+	case beforeOperator:
+		return attrTime.Before(first), nil
+	case afterOperator:
+		return attrTime.After(first), nil
+	case betweenOperator:
+		if len(values) < 2 {
+			return false, fmt.Errorf("%w: '%s' requires a start and end value", ErrInvalidTime, betweenOperator)
+		}
+		end, err := parseTime(values[1])
+		if err != nil {
+			return false, err
+		}
+		return !attrTime.Before(first) && attrTime.Before(end), nil
+	}
+	return false, fmt.Errorf("%w: %s", ErrUnknownOperator, op)
+}
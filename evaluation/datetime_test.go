@@ -0,0 +1,82 @@
+package evaluation
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func Test_toTime(t *testing.T) {
+	want := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	wantMillis := want.UnixMilli()
+	tests := []struct {
+		name    string
+		value   interface{}
+		wantErr bool
+	}{
+		{name: "time.Time", value: want},
+		{name: "*time.Time", value: &want},
+		{name: "RFC3339 string", value: "2025-01-01T00:00:00Z"},
+		{name: "RFC3339 string with offset", value: "2025-01-01T02:00:00+02:00"},
+		{name: "date string", value: "2025-01-01"},
+		{name: "epoch millis string", value: "1735689600000"},
+		{name: "epoch millis int", value: int(wantMillis)},
+		{name: "epoch millis int64", value: wantMillis},
+		{name: "epoch millis float64", value: float64(wantMillis)},
+		{name: "nil *time.Time", value: (*time.Time)(nil), wantErr: true},
+		{name: "garbage string", value: "next tuesday", wantErr: true},
+		{name: "unsupported type", value: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := toTime(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("toTime(%v) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidTime) {
+					t.Errorf("toTime(%v) error = %v, want ErrInvalidTime", tt.value, err)
+				}
+				return
+			}
+			if !got.Equal(want) {
+				t.Errorf("toTime(%v) = %v, want %v", tt.value, got, want)
+			}
+		})
+	}
+}
+
+func Test_evaluateTimeOperator(t *testing.T) {
+	created := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		op      string
+		attr    interface{}
+		values  []string
+		want    bool
+		wantErr bool
+	}{
+		{name: "before", op: beforeOperator, attr: created, values: []string{"2025-01-01T00:00:00Z"}, want: true},
+		{name: "before - negative path", op: beforeOperator, attr: created, values: []string{"2024-01-01"}, want: false},
+		{name: "before is exclusive", op: beforeOperator, attr: created, values: []string{"2024-06-15T12:00:00Z"}, want: false},
+		{name: "after", op: afterOperator, attr: created.UnixMilli(), values: []string{"2024-01-01"}, want: true},
+		{name: "after - negative path", op: afterOperator, attr: "2024-06-15T12:00:00Z", values: []string{"1735689600000"}, want: false},
+		{name: "between", op: betweenOperator, attr: created, values: []string{"2024-06-01", "2024-07-01"}, want: true},
+		{name: "between includes start", op: betweenOperator, attr: created, values: []string{"2024-06-15T12:00:00Z", "2024-07-01"}, want: true},
+		{name: "between excludes end", op: betweenOperator, attr: created, values: []string{"2024-06-01", "2024-06-15T12:00:00Z"}, want: false},
+		{name: "between requires two values", op: betweenOperator, attr: created, values: []string{"2024-06-01"}, wantErr: true},
+		{name: "invalid attribute", op: beforeOperator, attr: "yesterday", values: []string{"2024-06-01"}, wantErr: true},
+		{name: "invalid clause value", op: afterOperator, attr: created, values: []string{"soon"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := evaluateTimeOperator(tt.op, tt.attr, tt.values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("evaluateTimeOperator() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("evaluateTimeOperator() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
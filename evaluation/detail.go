@@ -0,0 +1,91 @@
+package evaluation
+
+import (
+	"errors"
+
+	"github.com/harness/ff-golang-server-sdk/rest"
+)
+
+// ReasonKind describes why a variation was served for a flag
+type ReasonKind string
+
+const (
+	// ReasonFlagOff the flag is off so the off variation was served
+	ReasonFlagOff ReasonKind = "FLAG_OFF"
+	// ReasonTargetMatch the target was listed, directly or via a group, in the flag's variation to target map
+	ReasonTargetMatch ReasonKind = "TARGET_MATCH"
+	// ReasonRuleMatch the target matched one of the flag's serving rules
+	ReasonRuleMatch ReasonKind = "RULE_MATCH"
+	// ReasonPercentageRollout the variation was picked by bucketing the target into a percentage rollout
+	ReasonPercentageRollout ReasonKind = "PERCENTAGE_ROLLOUT"
+	// ReasonDefaultServe no target or rule matched so the flag's default serve was used
+	ReasonDefaultServe ReasonKind = "DEFAULT_SERVE"
+	// ReasonPrerequisiteFailed a prerequisite flag did not serve one of the required variations
+	ReasonPrerequisiteFailed ReasonKind = "PREREQUISITE_FAILED"
+	// ReasonError the flag could not be evaluated and the default value was returned
+	ReasonError ReasonKind = "ERROR"
+)
+
+// ErrorKind categorises the error when Reason.Kind is ReasonError
+type ErrorKind string
+
+const (
+	// ErrorKindNone no error occurred
+	ErrorKindNone ErrorKind = ""
+	// ErrorKindClientNotReady the client has not finished initializing
+	ErrorKindClientNotReady ErrorKind = "CLIENT_NOT_READY"
+	// ErrorKindQueryProviderMissing the evaluator has no repository to query
+	ErrorKindQueryProviderMissing ErrorKind = "QUERY_PROVIDER_MISSING"
+	// ErrorKindFlagNotFound the flag doesn't exist
+	ErrorKindFlagNotFound ErrorKind = "FLAG_NOT_FOUND"
+	// ErrorKindVariationNotFound the flag served a variation that it doesn't define
+	ErrorKindVariationNotFound ErrorKind = "VARIATION_NOT_FOUND"
+	// ErrorKindWrongType the variation value couldn't be converted to the requested type
+	ErrorKindWrongType ErrorKind = "WRONG_TYPE"
+	// ErrorKindPrerequisiteCycle the flag's prerequisites form a cycle so the off variation was served
+	ErrorKindPrerequisiteCycle ErrorKind = "PREREQUISITE_CYCLE"
+	// ErrorKindPrerequisiteDepthExceeded the flag's prerequisite chain is too deep so the off variation was served
+	ErrorKindPrerequisiteDepthExceeded ErrorKind = "PREREQUISITE_DEPTH_EXCEEDED"
+	// ErrorKindGeneral any other evaluation error
+	ErrorKindGeneral ErrorKind = "GENERAL"
+)
+
+// Reason explains the outcome of a flag evaluation
+type Reason struct {
+	Kind ReasonKind
+	// RuleID is the ServingRule.RuleId of the matching rule, set when a rule was matched
+	RuleID string
+	// ErrorKind is set when Kind is ReasonError
+	ErrorKind ErrorKind
+}
+
+// EvaluationDetail holds the evaluated value together with the variation that was served
+// and the reason it was chosen
+type EvaluationDetail[T any] struct {
+	Value     T
+	Variation rest.Variation
+	Reason    Reason
+}
+
+// ErrorReason returns a ReasonError reason with the error kind derived from err
+func ErrorReason(err error) Reason {
+	kind := ErrorKindGeneral
+	switch {
+	case errors.Is(err, ErrQueryProviderMissing):
+		kind = ErrorKindQueryProviderMissing
+	case errors.Is(err, ErrVariationNotFound):
+		kind = ErrorKindVariationNotFound
+	case errors.Is(err, ErrPrerequisiteCycle):
+		kind = ErrorKindPrerequisiteCycle
+	case errors.Is(err, ErrPrerequisiteDepthExceeded):
+		kind = ErrorKindPrerequisiteDepthExceeded
+	}
+	return Reason{Kind: ReasonError, ErrorKind: kind}
+}
+
+func ruleID(rule *rest.ServingRule) string {
+	if rule == nil || rule.RuleId == nil {
+		return ""
+	}
+	return *rule.RuleId
+}
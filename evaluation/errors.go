@@ -11,4 +11,18 @@ var (
 	ErrEvaluationFlag = errors.New("error while evaluating flag")
 	// ErrFlagKindMismatch ...
 	ErrFlagKindMismatch = errors.New("flag kind mismatch")
+	// ErrUnknownOperator ...
+	ErrUnknownOperator = errors.New("unknown clause operator")
+	// ErrInvalidSemver ...
+	ErrInvalidSemver = errors.New("invalid semantic version")
+	// ErrInvalidTime ...
+	ErrInvalidTime = errors.New("invalid time")
+	// ErrInvalidRegex ...
+	ErrInvalidRegex = errors.New("invalid regular expression")
+	// ErrCustomOperatorPanic ...
+	ErrCustomOperatorPanic = errors.New("custom operator panicked")
+	// ErrPrerequisiteCycle ...
+	ErrPrerequisiteCycle = errors.New("prerequisite cycle detected")
+	// ErrPrerequisiteDepthExceeded ...
+	ErrPrerequisiteDepthExceeded = errors.New("prerequisite depth exceeded")
 )
@@ -13,6 +13,7 @@ import (
 	"github.com/harness/ff-golang-server-sdk/logger"
 
 	"github.com/harness/ff-golang-server-sdk/rest"
+	"github.com/harness/ff-golang-server-sdk/types"
 )
 
 const (
@@ -23,6 +24,15 @@ const (
 	inOperator             = "in"
 	equalOperator          = "equal"
 	gtOperator             = "gt"
+	gteOperator            = "gte"
+	ltOperator             = "lt"
+	lteOperator            = "lte"
+	semverEqualOperator    = "semver_eq"
+	semverGtOperator       = "semver_gt"
+	semverLtOperator       = "semver_lt"
+	beforeOperator         = "before"
+	afterOperator          = "after"
+	betweenOperator        = "between"
 	startsWithOperator     = "starts_with"
 	endsWithOperator       = "ends_with"
 	containsOperator       = "contains"
@@ -41,6 +51,12 @@ type Query interface {
 	GetFlagMap() (map[string]*rest.FeatureConfig, error)
 }
 
+// RegexProvider is optionally implemented by a Query to supply 'match' clause patterns that were
+// compiled when flags and segments were stored. A known but invalid pattern is returned as nil.
+type RegexProvider interface {
+	GetRegex(pattern string) (*regexp.Regexp, bool)
+}
+
 // FlagVariations list of FlagVariations
 type FlagVariations []FlagVariation
 
@@ -64,15 +80,27 @@ type PostEvaluateCallback interface {
 	PostEvaluateProcessor(data *PostEvalData)
 }
 
+// CustomOperator matches a target attribute value against the clause values for operators
+// the evaluator doesn't implement itself
+type CustomOperator func(attrValue interface{}, values []string) bool
+
 // Evaluator engine evaluates flag from provided query
 type Evaluator struct {
 	query            Query
 	postEvalCallback PostEvaluateCallback
 	logger           logger.Logger
+	customOperators  map[string]CustomOperator
 }
 
 // NewEvaluator constructs evaluator with query instance
 func NewEvaluator(query Query, postEvalCallback PostEvaluateCallback, logger logger.Logger) (*Evaluator, error) {
+	return NewEvaluatorWithOperators(query, postEvalCallback, logger, nil)
+}
+
+// NewEvaluatorWithOperators constructs evaluator with query instance and a registry of custom
+// clause operators, which are consulted for any Clause.Op the evaluator doesn't recognise
+func NewEvaluatorWithOperators(query Query, postEvalCallback PostEvaluateCallback, logger logger.Logger,
+	customOperators map[string]CustomOperator) (*Evaluator, error) {
 	if query == nil {
 		return nil, ErrQueryProviderMissing
 	}
@@ -80,6 +108,7 @@ func NewEvaluator(query Query, postEvalCallback PostEvaluateCallback, logger log
 		logger:           logger,
 		query:            query,
 		postEvalCallback: postEvalCallback,
+		customOperators:  customOperators,
 	}, nil
 }
 
@@ -88,41 +117,166 @@ func (e Evaluator) evaluateClause(clause *rest.Clause, target *Target) bool {
 		return false
 	}
 
-	value := clause.Values[0]
-	attrValue := getAttrValue(target, clause.Attribute)
-
-	if clause.Op != segmentMatchOperator && attrValue == "" {
+	matched, err := e.evaluateOperator(clause, target)
+	if err != nil {
+		e.logger.Warnf("%s: clause (%v)", err, *clause)
 		return false
 	}
 
+	// a negated clause is the inverse of its operator e.g. 'in' becomes 'not in'
+	if clause.Negate {
+		return !matched
+	}
+	return matched
+}
+
+func (e Evaluator) evaluateOperator(clause *rest.Clause, target *Target) (bool, error) {
+	if clause.Op == segmentMatchOperator {
+		return e.isTargetIncludedOrExcludedInSegment(clause.Values, target), nil
+	}
+
+	attr, ok := getRawAttrValue(target, clause.Attribute)
+	if !ok {
+		return false, nil
+	}
+
+	elements, isSlice := sliceElements(attr)
+	if !isSlice {
+		return e.evaluateValue(clause, attr)
+	}
+
+	// list attributes match if any element matches, so a negated clause matches when none do.
+	// Elements that can't be evaluated are skipped, but if nothing matched the error is returned
+	// so that a negated clause doesn't match on bad data.
+	var firstErr error
+	for _, element := range elements {
+		matched, err := e.evaluateValue(clause, element)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, firstErr
+}
+
+// evaluateValue applies the clause operator to a single attribute value
+func (e Evaluator) evaluateValue(clause *rest.Clause, attr interface{}) (bool, error) {
+	value := clause.Values[0]
+	attrValue := attrValueToString(attr)
+	if attr == nil || attrValue == "" {
+		return false, nil
+	}
+
 	switch clause.Op {
 	case startsWithOperator:
-		return strings.HasPrefix(attrValue, value)
+		return strings.HasPrefix(attrValue, value), nil
 	case endsWithOperator:
-		return strings.HasSuffix(attrValue, value)
+		return strings.HasSuffix(attrValue, value), nil
 	case matchOperator:
-		found, err := regexp.MatchString(value, attrValue)
-		return err == nil && found
+		// an invalid pattern is reported as an error so the clause never matches, even when negated
+		return e.matchRegex(value, attrValue)
 	case containsOperator:
-		return strings.Contains(attrValue, value)
+		return strings.Contains(attrValue, value), nil
 	case equalOperator:
-		return strings.EqualFold(attrValue, value)
+		return strings.EqualFold(attrValue, value), nil
 	case equalSensitiveOperator:
-		return attrValue == value
+		return attrValue == value, nil
 	case inOperator:
 		for _, val := range clause.Values {
 			if val == attrValue {
-				return true
+				return true, nil
 			}
 		}
-		return false
-	case gtOperator:
-		return attrValue > value
-	case segmentMatchOperator:
-		return e.isTargetIncludedOrExcludedInSegment(clause.Values, target)
+		return false, nil
+	case gtOperator, gteOperator, ltOperator, lteOperator:
+		return compareAttr(clause.Op, attr, attrValue, clause.Values), nil
+	case semverEqualOperator, semverGtOperator, semverLtOperator:
+		// a malformed version is reported as an error so the clause never matches, even when negated
+		cmp, err := compareSemver(attrValue, value)
+		if err != nil {
+			return false, err
+		}
+		switch clause.Op {
+		case semverGtOperator:
+			return cmp > 0, nil
+		case semverLtOperator:
+			return cmp < 0, nil
+		default:
+			return cmp == 0, nil
+		}
+	case beforeOperator, afterOperator, betweenOperator:
+		// as with semver, an unparseable time never matches
+		return evaluateTimeOperator(clause.Op, attr, clause.Values)
 	default:
+		if fn, ok := e.customOperators[clause.Op]; ok {
+			return evaluateCustomOperator(fn, attr, clause.Values)
+		}
+		return false, fmt.Errorf("%w: %s", ErrUnknownOperator, clause.Op)
+	}
+}
+
+// matchRegex uses the precompiled pattern when the query provides one, falling back to compiling it
+func (e Evaluator) matchRegex(pattern, attrValue string) (bool, error) {
+	if provider, ok := e.query.(RegexProvider); ok {
+		if re, ok := provider.GetRegex(pattern); ok {
+			if re == nil {
+				return false, fmt.Errorf("%w: %s", ErrInvalidRegex, pattern)
+			}
+			return re.MatchString(attrValue), nil
+		}
+	}
+	found, err := regexp.MatchString(pattern, attrValue)
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrInvalidRegex, err)
+	}
+	return found, nil
+}
+
+// evaluateCustomOperator runs a user supplied operator, a panic is treated as a non-match so a faulty
+// operator can't take down the caller's evaluation
+func evaluateCustomOperator(fn CustomOperator, attr interface{}, values []string) (matched bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			matched, err = false, fmt.Errorf("%w: %v", ErrCustomOperatorPanic, r)
+		}
+	}()
+	return fn(attr, values), nil
+}
+
+// compareAttr applies an ordering operator to the attribute value, numbers compare numerically and
+// anything else compares as case-sensitive strings
+func compareAttr(op string, attr interface{}, attrValue string, values []string) bool {
+	operand, ok := attrOperand(attr).(types.Number)
+	if !ok {
+		value := values[0]
+		switch op {
+		case gtOperator:
+			return attrValue > value
+		case gteOperator:
+			return attrValue >= value
+		case ltOperator:
+			return attrValue < value
+		case lteOperator:
+			return attrValue <= value
+		}
 		return false
 	}
+	switch op {
+	case gtOperator:
+		return operand.GreaterThan(values)
+	case gteOperator:
+		return operand.GreaterThanEqual(values)
+	case ltOperator:
+		return operand.LessThan(values)
+	case lteOperator:
+		return operand.LessThanEqual(values)
+	}
+	return false
 }
 
 func (e Evaluator) evaluateClauses(clauses []rest.Clause, target *Target) bool {
@@ -138,10 +292,10 @@ func (e Evaluator) evaluateRule(servingRule *rest.ServingRule, target *Target) b
 	return e.evaluateClauses(servingRule.Clauses, target)
 }
 
-func (e Evaluator) evaluateRules(servingRules []rest.ServingRule, target *Target) string {
+func (e Evaluator) evaluateRules(servingRules []rest.ServingRule, target *Target) (string, Reason) {
 	if target == nil || servingRules == nil {
 		e.logger.Debugf("Serving Rules or Target are Nil")
-		return ""
+		return "", Reason{}
 	}
 
 	for i := range servingRules {
@@ -156,18 +310,18 @@ func (e Evaluator) evaluateRules(servingRules []rest.ServingRule, target *Target
 
 		// rule matched, check if there is distribution
 		if rule.Serve.Distribution != nil {
-			return evaluateDistribution(rule.Serve.Distribution, target)
+			return evaluateDistribution(rule.Serve.Distribution, target), Reason{Kind: ReasonPercentageRollout, RuleID: ruleID(&rule)}
 		}
 
 		// rule matched, here must be variation if distribution is undefined or null
 		if rule.Serve.Variation != nil {
 			e.logger.Debugf("Rule Matched for Target(%v), Variation returned (%v)", target, *rule.Serve.Variation)
-			return *rule.Serve.Variation
+			return *rule.Serve.Variation, Reason{Kind: ReasonRuleMatch, RuleID: ruleID(&rule)}
 		} else {
 			e.logger.Warnf("No Variation on Serve for Rule (%v), Target (%v)", rule, target)
 		}
 	}
-	return ""
+	return "", Reason{}
 }
 
 // evaluateGroupRulesV2 evaluates the group rules using AND logic instead of OR.
@@ -221,30 +375,39 @@ func (e Evaluator) evaluateVariationMap(variationsMap []rest.VariationMap, targe
 	return ""
 }
 
-func (e Evaluator) evaluateFlag(fc rest.FeatureConfig, target *Target) (rest.Variation, error) {
+func (e Evaluator) evaluateFlag(fc rest.FeatureConfig, target *Target) (rest.Variation, Reason, error) {
 	var variation = fc.OffVariation
+	reason := Reason{Kind: ReasonFlagOff}
 	if fc.State == rest.FeatureStateOn {
 		variation = ""
 		if fc.VariationToTargetMap != nil {
 			variation = e.evaluateVariationMap(*fc.VariationToTargetMap, target)
+			reason = Reason{Kind: ReasonTargetMatch}
 		}
 		if variation == "" && fc.Rules != nil {
-			variation = e.evaluateRules(*fc.Rules, target)
+			variation, reason = e.evaluateRules(*fc.Rules, target)
 		}
 		if variation == "" {
 			variation = evaluateDistribution(fc.DefaultServe.Distribution, target)
+			reason = Reason{Kind: ReasonPercentageRollout}
 		}
 		if variation == "" && fc.DefaultServe.Variation != nil {
 			variation = *fc.DefaultServe.Variation
+			reason = Reason{Kind: ReasonDefaultServe}
 		}
 	} else {
 		e.logger.Debugf("Flag is off: Flag(%s)", fc.Feature)
 	}
 
 	if variation != "" {
-		return findVariation(fc.Variations, variation)
+		v, err := findVariation(fc.Variations, variation)
+		if err != nil {
+			return v, ErrorReason(err), err
+		}
+		return v, reason, nil
 	}
-	return rest.Variation{}, fmt.Errorf("%w: %s", ErrEvaluationFlag, fc.Feature)
+	err := fmt.Errorf("%w: %s", ErrEvaluationFlag, fc.Feature)
+	return rest.Variation{}, ErrorReason(err), err
 }
 
 func (e Evaluator) isTargetIncludedOrExcludedInSegment(segmentList []string, target *Target) bool {
@@ -301,6 +464,12 @@ func (e Evaluator) isTargetIncludedOrExcludedInSegment(segmentList []string, tar
 }
 
 func (e Evaluator) checkPreRequisite(fc *rest.FeatureConfig, target *Target) (bool, error) {
+	return e.checkPreRequisitePath(fc, target, []string{fc.Feature})
+}
+
+// checkPreRequisitePath checks the prerequisites of fc, where path holds the flags already visited
+// to reach it so that cycles are reported as an error rather than recursing forever
+func (e Evaluator) checkPreRequisitePath(fc *rest.FeatureConfig, target *Target, path []string) (bool, error) {
 	if e.query == nil {
 		e.logger.Errorf(ErrQueryProviderMissing.Error())
 		return true, ErrQueryProviderMissing
@@ -313,6 +482,9 @@ func (e Evaluator) checkPreRequisite(fc *rest.FeatureConfig, target *Target) (bo
 			fc.Feature)
 		for _, pre := range *prerequisites {
 			prereqFeature := pre.Feature
+			if err := checkPrerequisitePath(path, prereqFeature); err != nil {
+				return false, err
+			}
 			prereqFeatureConfig, err := e.query.GetFlag(prereqFeature)
 			if err != nil {
 				e.logger.Errorf(
@@ -320,7 +492,7 @@ func (e Evaluator) checkPreRequisite(fc *rest.FeatureConfig, target *Target) (bo
 				return true, nil
 			}
 
-			prereqEvaluatedVariation, err := e.evaluateFlag(prereqFeatureConfig, target)
+			prereqEvaluatedVariation, _, err := e.evaluateFlag(prereqFeatureConfig, target)
 			if err != nil {
 				e.logger.Errorf(
 					"Could not evaluate the prerequisite details of feature flag : %v", prereqFeature)
@@ -343,8 +515,8 @@ func (e Evaluator) checkPreRequisite(fc *rest.FeatureConfig, target *Target) (bo
 			if !contains(validPrereqVariations, prereqEvaluatedVariation.Identifier) {
 				return false, nil
 			}
-			if r, _ := e.checkPreRequisite(&prereqFeatureConfig, target); !r {
-				return false, nil
+			if r, err := e.checkPreRequisitePath(&prereqFeatureConfig, target, appendPath(path, prereqFeature)); err != nil || !r {
+				return false, err
 			}
 		}
 	}
@@ -359,12 +531,13 @@ func (e Evaluator) EvaluateAll(target *Target) (FlagVariations, error) {
 // takes uses feature store.List function to get all the flags.
 func (e Evaluator) evaluateAll(target *Target) ([]FlagVariation, error) {
 	var variations []FlagVariation
-	flags, err := e.query.GetFlagMap()
+	flags, err := e.query.GetFlags()
 	if err != nil {
 		return variations, err
 	}
-	for _, f := range flags {
-		v, err := e.getVariationForTheFlag(f, target)
+	for i := range flags {
+		f := &flags[i]
+		v, _, err := e.getVariationForTheFlag(f, target)
 		if err != nil {
 			e.logger.Warnf("Error Getting Variation for Flag: Flag (%s), Target (%v), Err: %s", f.Feature, target, err)
 		}
@@ -379,42 +552,62 @@ func (e Evaluator) Evaluate(identifier string, target *Target) (FlagVariation, e
 	return e.evaluate(identifier, target)
 }
 
+// EvaluateDetail evaluates the flag and also returns the reason the variation was served.
+func (e Evaluator) EvaluateDetail(identifier string, target *Target) (FlagVariation, Reason, error) {
+	return e.evaluateDetail(identifier, target)
+}
+
 // this is evaluating flag.
 func (e Evaluator) evaluate(identifier string, target *Target) (FlagVariation, error) {
+	flagVariation, _, err := e.evaluateDetail(identifier, target)
+	return flagVariation, err
+}
+
+func (e Evaluator) evaluateDetail(identifier string, target *Target) (FlagVariation, Reason, error) {
 	e.logger.Debugf("Evaluating: Flag(%s) Target(%v)", identifier, target)
 	if e.query == nil {
 		e.logger.Errorf(ErrQueryProviderMissing.Error())
-		return FlagVariation{}, ErrQueryProviderMissing
+		return FlagVariation{}, ErrorReason(ErrQueryProviderMissing), ErrQueryProviderMissing
 	}
 	flag, err := e.query.GetFlag(identifier)
 	if err != nil {
 		e.logger.Warnf("Error Getting Flag: Flag (%s), Target(%v), Err: %s", identifier, target, err)
-		return FlagVariation{}, err
+		return FlagVariation{}, Reason{Kind: ReasonError, ErrorKind: ErrorKindFlagNotFound}, err
 	}
 
-	variation, err := e.getVariationForTheFlag(&flag, target)
+	variation, reason, err := e.getVariationForTheFlag(&flag, target)
 	if err != nil {
 		e.logger.Warnf("Error Getting Variation for Flag: Flag (%s), Target(%v), Err: %s", identifier, target, err)
-		return FlagVariation{}, err
+		return FlagVariation{}, reason, err
 	}
-	return FlagVariation{flag.Feature, flag.Kind, variation}, nil
+	return FlagVariation{flag.Feature, flag.Kind, variation}, reason, nil
 }
 
 // evaluates the flag and returns a proper variation.
-func (e Evaluator) getVariationForTheFlag(flag *rest.FeatureConfig, target *Target) (rest.Variation, error) {
+func (e Evaluator) getVariationForTheFlag(flag *rest.FeatureConfig, target *Target) (rest.Variation, Reason, error) {
 	if flag == nil {
-		return rest.Variation{}, ErrNilFlag
+		return rest.Variation{}, ErrorReason(ErrNilFlag), ErrNilFlag
 	}
 
 	if flag.Prerequisites != nil {
 		prereq, err := e.checkPreRequisite(flag, target)
 		if err != nil || !prereq {
-			return findVariation(flag.Variations, flag.OffVariation)
+			reason := Reason{Kind: ReasonPrerequisiteFailed}
+			if errors.Is(err, ErrPrerequisiteCycle) || errors.Is(err, ErrPrerequisiteDepthExceeded) {
+				// a misconfigured prerequisite graph serves the off variation, but says why
+				e.logger.Errorf("Invalid prerequisites for flag %s, serving off variation: %s", flag.Feature, err)
+				reason = ErrorReason(err)
+			}
+			variation, err := findVariation(flag.Variations, flag.OffVariation)
+			if err != nil {
+				return variation, ErrorReason(err), err
+			}
+			return variation, reason, nil
 		}
 	}
-	variation, err := e.evaluateFlag(*flag, target)
+	variation, reason, err := e.evaluateFlag(*flag, target)
 	if err != nil {
-		return rest.Variation{}, err
+		return rest.Variation{}, reason, err
 	}
 	if e.postEvalCallback != nil {
 		data := PostEvalData{
@@ -425,67 +618,105 @@ func (e Evaluator) getVariationForTheFlag(flag *rest.FeatureConfig, target *Targ
 
 		e.postEvalCallback.PostEvaluateProcessor(&data)
 	}
-	return variation, nil
+	return variation, reason, nil
 }
 
 // BoolVariation returns boolean evaluation for target
 func (e Evaluator) BoolVariation(identifier string, target *Target, defaultValue bool) (bool, error) {
-	//flagVariation, err := e.evaluate(identifier, target, "boolean")
-	flagVariation, err := e.evaluate(identifier, target)
+	detail, err := e.BoolVariationDetail(identifier, target, defaultValue)
+	return detail.Value, err
+}
+
+// BoolVariationDetail returns boolean evaluation for target along with the variation served and the reason
+func (e Evaluator) BoolVariationDetail(identifier string, target *Target, defaultValue bool) (EvaluationDetail[bool], error) {
+	flagVariation, reason, err := e.evaluateDetail(identifier, target)
 	if err != nil {
-		return defaultValue, err
+		return EvaluationDetail[bool]{Value: defaultValue, Reason: reason}, err
 	}
-	return strings.ToLower(flagVariation.Variation.Value) == "true", nil
+	return EvaluationDetail[bool]{
+		Value:     strings.ToLower(flagVariation.Variation.Value) == "true",
+		Variation: flagVariation.Variation,
+		Reason:    reason,
+	}, nil
 }
 
 // StringVariation returns string evaluation for target
 func (e Evaluator) StringVariation(identifier string, target *Target, defaultValue string) (string, error) {
-	flagVariation, err := e.evaluate(identifier, target)
+	detail, err := e.StringVariationDetail(identifier, target, defaultValue)
+	return detail.Value, err
+}
+
+// StringVariationDetail returns string evaluation for target along with the variation served and the reason
+func (e Evaluator) StringVariationDetail(identifier string, target *Target, defaultValue string) (EvaluationDetail[string], error) {
+	flagVariation, reason, err := e.evaluateDetail(identifier, target)
 	if err != nil {
-		return defaultValue, err
+		return EvaluationDetail[string]{Value: defaultValue, Reason: reason}, err
 	}
-	return flagVariation.Variation.Value, nil
+	return EvaluationDetail[string]{
+		Value:     flagVariation.Variation.Value,
+		Variation: flagVariation.Variation,
+		Reason:    reason,
+	}, nil
 }
 
 // IntVariation returns int evaluation for target
 func (e Evaluator) IntVariation(identifier string, target *Target, defaultValue int) (int, error) {
-	flagVariation, err := e.evaluate(identifier, target)
+	detail, err := e.IntVariationDetail(identifier, target, defaultValue)
+	return detail.Value, err
+}
+
+// IntVariationDetail returns int evaluation for target along with the variation served and the reason
+func (e Evaluator) IntVariationDetail(identifier string, target *Target, defaultValue int) (EvaluationDetail[int], error) {
+	flagVariation, reason, err := e.evaluateDetail(identifier, target)
 	if err != nil {
-		return defaultValue, err
+		return EvaluationDetail[int]{Value: defaultValue, Reason: reason}, err
 	}
 	val, err := strconv.Atoi(flagVariation.Variation.Value)
 	if err != nil {
-		return defaultValue, err
+		return EvaluationDetail[int]{Value: defaultValue, Variation: flagVariation.Variation, Reason: Reason{Kind: ReasonError, ErrorKind: ErrorKindWrongType}}, err
 	}
-	return val, nil
+	return EvaluationDetail[int]{Value: val, Variation: flagVariation.Variation, Reason: reason}, nil
 }
 
 // NumberVariation returns number evaluation for target
 func (e Evaluator) NumberVariation(identifier string, target *Target, defaultValue float64) (float64, error) {
+	detail, err := e.NumberVariationDetail(identifier, target, defaultValue)
+	return detail.Value, err
+}
+
+// NumberVariationDetail returns number evaluation for target along with the variation served and the reason
+func (e Evaluator) NumberVariationDetail(identifier string, target *Target, defaultValue float64) (EvaluationDetail[float64], error) {
 	//all numbers are stored as ints in the database
-	flagVariation, err := e.evaluate(identifier, target)
+	flagVariation, reason, err := e.evaluateDetail(identifier, target)
 	if err != nil {
-		return defaultValue, err
+		return EvaluationDetail[float64]{Value: defaultValue, Reason: reason}, err
 	}
 	val, err := strconv.ParseFloat(flagVariation.Variation.Value, 64)
 	if err != nil {
-		return defaultValue, err
+		return EvaluationDetail[float64]{Value: defaultValue, Variation: flagVariation.Variation, Reason: Reason{Kind: ReasonError, ErrorKind: ErrorKindWrongType}}, err
 	}
-	return val, nil
+	return EvaluationDetail[float64]{Value: val, Variation: flagVariation.Variation, Reason: reason}, nil
 }
 
 // JSONVariation returns json evaluation for target
 func (e Evaluator) JSONVariation(identifier string, target *Target,
 	defaultValue map[string]interface{}) (map[string]interface{}, error) {
-	flagVariation, err := e.evaluate(identifier, target)
+	detail, err := e.JSONVariationDetail(identifier, target, defaultValue)
+	return detail.Value, err
+}
+
+// JSONVariationDetail returns json evaluation for target along with the variation served and the reason
+func (e Evaluator) JSONVariationDetail(identifier string, target *Target,
+	defaultValue map[string]interface{}) (EvaluationDetail[map[string]interface{}], error) {
+	flagVariation, reason, err := e.evaluateDetail(identifier, target)
 	if err != nil {
-		return defaultValue, err
+		return EvaluationDetail[map[string]interface{}]{Value: defaultValue, Reason: reason}, err
 	}
 	val := make(map[string]interface{})
 	err = json.Unmarshal([]byte(flagVariation.Variation.Value), &val)
 	if err != nil {
-		return defaultValue, err
+		return EvaluationDetail[map[string]interface{}]{Value: defaultValue, Variation: flagVariation.Variation, Reason: Reason{Kind: ReasonError, ErrorKind: ErrorKindWrongType}}, err
 	}
 	e.logger.Debugf("%s Evaluated json flag successfully: '%s'", sdk_codes.EvaluationSuccess, identifier)
-	return val, nil
+	return EvaluationDetail[map[string]interface{}]{Value: val, Variation: flagVariation.Variation, Reason: reason}, nil
 }
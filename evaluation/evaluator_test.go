@@ -3,7 +3,9 @@ package evaluation
 import (
 	"fmt"
 	"reflect"
+	"regexp"
 	"testing"
+	"time"
 
 	"github.com/harness/ff-golang-server-sdk/logger"
 
@@ -373,7 +375,7 @@ func (m TestRepository) GetFlags() ([]rest.FeatureConfig, error) {
 }
 
 func (m TestRepository) GetFlagMap() (map[string]*rest.FeatureConfig, error) {
-	var flags map[string]*rest.FeatureConfig
+	flags := map[string]*rest.FeatureConfig{}
 	for _, f := range m.flags {
 		flags[f.Feature] = &f
 	}
@@ -722,67 +724,756 @@ func TestEvaluator_evaluateClause(t *testing.T) {
 			},
 			want: false,
 		},
+		{
+			name:   "check gt operator compares ints numerically",
+			fields: fields{},
+			args: args{
+				clause: &rest.Clause{
+					Attribute: "age",
+					Op:        gtOperator,
+					Values:    []string{"9"},
+				},
+				target: &Target{
+					Identifier: harness,
+					Attributes: &map[string]interface{}{
+						"age": 10,
+					},
+				},
+			},
+			want: true,
+		},
+		{
+			name:   "check gt operator compares floats numerically",
+			fields: fields{},
+			args: args{
+				clause: &rest.Clause{
+					Attribute: "age",
+					Op:        gtOperator,
+					Values:    []string{"9.75"},
+				},
+				target: &Target{
+					Identifier: harness,
+					Attributes: &map[string]interface{}{
+						"age": 10.5,
+					},
+				},
+			},
+			want: true,
+		},
+		{
+			name:   "check gt operator compares numeric strings numerically",
+			fields: fields{},
+			args: args{
+				clause: &rest.Clause{
+					Attribute: "age",
+					Op:        gtOperator,
+					Values:    []string{"9"},
+				},
+				target: &Target{
+					Identifier: harness,
+					Attributes: &map[string]interface{}{
+						"age": "10",
+					},
+				},
+			},
+			want: true,
+		},
+		{
+			name:   "check gt operator with int64 attribute",
+			fields: fields{},
+			args: args{
+				clause: &rest.Clause{
+					Attribute: "age",
+					Op:        gtOperator,
+					Values:    []string{"99"},
+				},
+				target: &Target{
+					Identifier: harness,
+					Attributes: &map[string]interface{}{
+						"age": int64(100),
+					},
+				},
+			},
+			want: true,
+		},
+		{
+			name:   "check gt operator with uint attribute",
+			fields: fields{},
+			args: args{
+				clause: &rest.Clause{
+					Attribute: "age",
+					Op:        gtOperator,
+					Values:    []string{"2"},
+				},
+				target: &Target{
+					Identifier: harness,
+					Attributes: &map[string]interface{}{
+						"age": uint(1),
+					},
+				},
+			},
+			want: false,
+		},
+		{
+			name:   "check gte operator when equal",
+			fields: fields{},
+			args: args{
+				clause: &rest.Clause{
+					Attribute: "age",
+					Op:        gteOperator,
+					Values:    []string{"18"},
+				},
+				target: &Target{
+					Identifier: harness,
+					Attributes: &map[string]interface{}{
+						"age": 18,
+					},
+				},
+			},
+			want: true,
+		},
+		{
+			name:   "check gte operator when less",
+			fields: fields{},
+			args: args{
+				clause: &rest.Clause{
+					Attribute: "age",
+					Op:        gteOperator,
+					Values:    []string{"18"},
+				},
+				target: &Target{
+					Identifier: harness,
+					Attributes: &map[string]interface{}{
+						"age": 17,
+					},
+				},
+			},
+			want: false,
+		},
+		{
+			name:   "check lt operator",
+			fields: fields{},
+			args: args{
+				clause: &rest.Clause{
+					Attribute: "age",
+					Op:        ltOperator,
+					Values:    []string{"10"},
+				},
+				target: &Target{
+					Identifier: harness,
+					Attributes: &map[string]interface{}{
+						"age": 9,
+					},
+				},
+			},
+			want: true,
+		},
+		{
+			name:   "check lt operator - negative path",
+			fields: fields{},
+			args: args{
+				clause: &rest.Clause{
+					Attribute: "age",
+					Op:        ltOperator,
+					Values:    []string{"9"},
+				},
+				target: &Target{
+					Identifier: harness,
+					Attributes: &map[string]interface{}{
+						"age": 10,
+					},
+				},
+			},
+			want: false,
+		},
+		{
+			name:   "check lte operator when equal",
+			fields: fields{},
+			args: args{
+				clause: &rest.Clause{
+					Attribute: "age",
+					Op:        lteOperator,
+					Values:    []string{"2.5"},
+				},
+				target: &Target{
+					Identifier: harness,
+					Attributes: &map[string]interface{}{
+						"age": 2.5,
+					},
+				},
+			},
+			want: true,
+		},
+		{
+			name:   "check lte operator when greater",
+			fields: fields{},
+			args: args{
+				clause: &rest.Clause{
+					Attribute: "age",
+					Op:        lteOperator,
+					Values:    []string{"2.5"},
+				},
+				target: &Target{
+					Identifier: harness,
+					Attributes: &map[string]interface{}{
+						"age": 3,
+					},
+				},
+			},
+			want: false,
+		},
+		{
+			name:   "check lt operator with non numeric clause value",
+			fields: fields{},
+			args: args{
+				clause: &rest.Clause{
+					Attribute: "age",
+					Op:        ltOperator,
+					Values:    []string{"nine"},
+				},
+				target: &Target{
+					Identifier: harness,
+					Attributes: &map[string]interface{}{
+						"age": 9,
+					},
+				},
+			},
+			want: false,
+		},
+		{
+			name:   "check lt operator compares strings",
+			fields: fields{},
+			args: args{
+				clause: &rest.Clause{
+					Attribute: "age",
+					Op:        ltOperator,
+					Values:    []string{"banana"},
+				},
+				target: &Target{
+					Identifier: harness,
+					Attributes: &map[string]interface{}{
+						"age": "apple",
+					},
+				},
+			},
+			want: true,
+		},
+		{
+			name:   "check gt operator compares strings case sensitively",
+			fields: fields{},
+			args: args{
+				clause: &rest.Clause{
+					Attribute: "age",
+					Op:        gtOperator,
+					Values:    []string{"Banana"},
+				},
+				target: &Target{
+					Identifier: harness,
+					Attributes: &map[string]interface{}{
+						"age": "apple",
+					},
+				},
+			},
+			want: true,
+		},
+		{
+			name:   "check semver_gt operator",
+			fields: fields{},
+			args: args{
+				clause: &rest.Clause{
+					Attribute: "appVersion",
+					Op:        semverGtOperator,
+					Values:    []string{"1.9.2"},
+				},
+				target: &Target{
+					Identifier: harness,
+					Attributes: &map[string]interface{}{
+						"appVersion": "1.10.0",
+					},
+				},
+			},
+			want: true,
+		},
+		{
+			name:   "check semver_gt operator - pre-release is lower than release",
+			fields: fields{},
+			args: args{
+				clause: &rest.Clause{
+					Attribute: "appVersion",
+					Op:        semverGtOperator,
+					Values:    []string{"2.0.0"},
+				},
+				target: &Target{
+					Identifier: harness,
+					Attributes: &map[string]interface{}{
+						"appVersion": "2.0.0-rc.1",
+					},
+				},
+			},
+			want: false,
+		},
+		{
+			name:   "check semver_lt operator",
+			fields: fields{},
+			args: args{
+				clause: &rest.Clause{
+					Attribute: "appVersion",
+					Op:        semverLtOperator,
+					Values:    []string{"1.10.0"},
+				},
+				target: &Target{
+					Identifier: harness,
+					Attributes: &map[string]interface{}{
+						"appVersion": "1.9.2",
+					},
+				},
+			},
+			want: true,
+		},
+		{
+			name:   "check semver_lt operator - negative path",
+			fields: fields{},
+			args: args{
+				clause: &rest.Clause{
+					Attribute: "appVersion",
+					Op:        semverLtOperator,
+					Values:    []string{"2.0.0-beta"},
+				},
+				target: &Target{
+					Identifier: harness,
+					Attributes: &map[string]interface{}{
+						"appVersion": "2.0.0",
+					},
+				},
+			},
+			want: false,
+		},
+		{
+			name:   "check semver_eq operator ignores build metadata",
+			fields: fields{},
+			args: args{
+				clause: &rest.Clause{
+					Attribute: "appVersion",
+					Op:        semverEqualOperator,
+					Values:    []string{"3.1.0"},
+				},
+				target: &Target{
+					Identifier: harness,
+					Attributes: &map[string]interface{}{
+						"appVersion": "v3.1.0+build.7",
+					},
+				},
+			},
+			want: true,
+		},
+		{
+			name:   "check semver_eq operator",
+			fields: fields{},
+			args: args{
+				clause: &rest.Clause{
+					Attribute: "appVersion",
+					Op:        semverEqualOperator,
+					Values:    []string{"3.1.0"},
+				},
+				target: &Target{
+					Identifier: harness,
+					Attributes: &map[string]interface{}{
+						"appVersion": "3.1.1",
+					},
+				},
+			},
+			want: false,
+		},
+		{
+			name:   "check semver operator with malformed attribute is a non-match",
+			fields: fields{},
+			args: args{
+				clause: &rest.Clause{
+					Attribute: "appVersion",
+					Op:        semverGtOperator,
+					Values:    []string{"1.0.0"},
+				},
+				target: &Target{
+					Identifier: harness,
+					Attributes: &map[string]interface{}{
+						"appVersion": "not-a-version",
+					},
+				},
+			},
+			want: false,
+		},
+		{
+			name:   "check semver operator with malformed clause value is a non-match",
+			fields: fields{},
+			args: args{
+				clause: &rest.Clause{
+					Attribute: "appVersion",
+					Op:        semverLtOperator,
+					Values:    []string{"latest"},
+				},
+				target: &Target{
+					Identifier: harness,
+					Attributes: &map[string]interface{}{
+						"appVersion": "1.0.0",
+					},
+				},
+			},
+			want: false,
+		},
+		{
+			name:   "check negated semver operator with malformed attribute is a non-match",
+			fields: fields{},
+			args: args{
+				clause: &rest.Clause{
+					Attribute: "appVersion",
+					Op:        semverGtOperator,
+					Negate:    true,
+					Values:    []string{"1.0.0"},
+				},
+				target: &Target{
+					Identifier: harness,
+					Attributes: &map[string]interface{}{
+						"appVersion": "1..0",
+					},
+				},
+			},
+			want: false,
+		},
+		{
+			name:   "check negated semver_lt operator",
+			fields: fields{},
+			args: args{
+				clause: &rest.Clause{
+					Attribute: "appVersion",
+					Op:        semverLtOperator,
+					Negate:    true,
+					Values:    []string{"1.0.0"},
+				},
+				target: &Target{
+					Identifier: harness,
+					Attributes: &map[string]interface{}{
+						"appVersion": "2.0.0",
+					},
+				},
+			},
+			want: true,
+		},
+		{
+			name:   "check before operator with time.Time attribute",
+			fields: fields{},
+			args: args{
+				clause: &rest.Clause{
+					Attribute: "createdAt",
+					Op:        beforeOperator,
+					Values:    []string{"2025-01-01T00:00:00Z"},
+				},
+				target: &Target{
+					Identifier: harness,
+					Attributes: &map[string]interface{}{
+						"createdAt": time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC),
+					},
+				},
+			},
+			want: true,
+		},
+		{
+			name:   "check before operator with RFC3339 attribute",
+			fields: fields{},
+			args: args{
+				clause: &rest.Clause{
+					Attribute: "createdAt",
+					Op:        beforeOperator,
+					Values:    []string{"2025-01-01"},
+				},
+				target: &Target{
+					Identifier: harness,
+					Attributes: &map[string]interface{}{
+						"createdAt": "2025-03-01T00:00:00Z",
+					},
+				},
+			},
+			want: false,
+		},
+		{
+			name:   "check after operator with epoch millis attribute",
+			fields: fields{},
+			args: args{
+				clause: &rest.Clause{
+					Attribute: "createdAt",
+					Op:        afterOperator,
+					Values:    []string{"2025-01-01T00:00:00Z"},
+				},
+				target: &Target{
+					Identifier: harness,
+					Attributes: &map[string]interface{}{
+						"createdAt": int64(1735689600001),
+					},
+				},
+			},
+			want: true,
+		},
+		{
+			name:   "check between operator",
+			fields: fields{},
+			args: args{
+				clause: &rest.Clause{
+					Attribute: "createdAt",
+					Op:        betweenOperator,
+					Values:    []string{"2025-01-01", "2025-02-01"},
+				},
+				target: &Target{
+					Identifier: harness,
+					Attributes: &map[string]interface{}{
+						"createdAt": "2025-01-15",
+					},
+				},
+			},
+			want: true,
+		},
+		{
+			name:   "check between operator outside window",
+			fields: fields{},
+			args: args{
+				clause: &rest.Clause{
+					Attribute: "createdAt",
+					Op:        betweenOperator,
+					Values:    []string{"2025-01-01", "2025-02-01"},
+				},
+				target: &Target{
+					Identifier: harness,
+					Attributes: &map[string]interface{}{
+						"createdAt": "2025-02-15",
+					},
+				},
+			},
+			want: false,
+		},
+		{
+			name:   "check negated after operator with invalid time is a non-match",
+			fields: fields{},
+			args: args{
+				clause: &rest.Clause{
+					Attribute: "createdAt",
+					Op:        afterOperator,
+					Negate:    true,
+					Values:    []string{"2025-01-01"},
+				},
+				target: &Target{
+					Identifier: harness,
+					Attributes: &map[string]interface{}{
+						"createdAt": "not a time",
+					},
+				},
+			},
+			want: false,
+		},
 		{
 			name:   "check starts with operator",
 			fields: fields{},
 			args: args{
 				clause: &rest.Clause{
 					Attribute: identifier,
-					Op:        startsWithOperator,
-					Values:    []string{harness},
+					Op:        startsWithOperator,
+					Values:    []string{harness},
+				},
+				target: &Target{
+					Identifier: harness + " - wings software",
+				},
+			},
+			want: true,
+		},
+		{
+			name:   "check ends with operator",
+			fields: fields{},
+			args: args{
+				clause: &rest.Clause{
+					Attribute: identifier,
+					Op:        endsWithOperator,
+					Values:    []string{harness},
+				},
+				target: &Target{
+					Identifier: "wings software - " + harness,
+				},
+			},
+			want: true,
+		},
+		{
+			name:   "check contains operator",
+			fields: fields{},
+			args: args{
+				clause: &rest.Clause{
+					Attribute: identifier,
+					Op:        containsOperator,
+					Values:    []string{harness},
+				},
+				target: &Target{
+					Identifier: "wings " + harness + " software",
+				},
+			},
+			want: true,
+		},
+		{
+			name: "check segments operator",
+			fields: fields{
+				query: testRepo,
+			},
+			args: args{
+				clause: &rest.Clause{
+					Op:     segmentMatchOperator,
+					Values: []string{beta},
+				},
+				target: &Target{
+					Identifier: harness,
+				},
+			},
+			want: true,
+		},
+		{
+			name:   "check negated in operator (not in)",
+			fields: fields{},
+			args: args{
+				clause: &rest.Clause{
+					Attribute: identifier,
+					Op:        inOperator,
+					Negate:    true,
+					Values:    []string{"harness1", "wings-software"},
 				},
 				target: &Target{
-					Identifier: harness + " - wings software",
+					Identifier: harness,
 				},
 			},
 			want: true,
 		},
 		{
-			name:   "check ends with operator",
+			name:   "check negated in operator when value is present",
 			fields: fields{},
 			args: args{
 				clause: &rest.Clause{
 					Attribute: identifier,
-					Op:        endsWithOperator,
+					Op:        inOperator,
+					Negate:    true,
+					Values:    []string{harness, "wings-software"},
+				},
+				target: &Target{
+					Identifier: harness,
+				},
+			},
+			want: false,
+		},
+		{
+			name:   "check negated contains operator",
+			fields: fields{},
+			args: args{
+				clause: &rest.Clause{
+					Attribute: identifier,
+					Op:        containsOperator,
+					Negate:    true,
 					Values:    []string{harness},
 				},
 				target: &Target{
-					Identifier: "wings software - " + harness,
+					Identifier: "wings software",
 				},
 			},
 			want: true,
 		},
 		{
-			name:   "check contains operator",
+			name:   "check negated starts with operator",
 			fields: fields{},
 			args: args{
 				clause: &rest.Clause{
 					Attribute: identifier,
-					Op:        containsOperator,
+					Op:        startsWithOperator,
+					Negate:    true,
 					Values:    []string{harness},
 				},
 				target: &Target{
-					Identifier: "wings " + harness + " software",
+					Identifier: harness + " - wings software",
+				},
+			},
+			want: false,
+		},
+		{
+			name:   "check negated equal operator when attribute is missing",
+			fields: fields{},
+			args: args{
+				clause: &rest.Clause{
+					Attribute: "email",
+					Op:        equalOperator,
+					Negate:    true,
+					Values:    []string{"john@harness.io"},
+				},
+				target: &Target{
+					Identifier: harness,
 				},
 			},
 			want: true,
 		},
 		{
-			name: "check segments operator",
+			name: "check negated segments operator",
 			fields: fields{
 				query: testRepo,
 			},
 			args: args{
 				clause: &rest.Clause{
 					Op:     segmentMatchOperator,
+					Negate: true,
 					Values: []string{beta},
 				},
 				target: &Target{
 					Identifier: harness,
 				},
 			},
+			want: false,
+		},
+		{
+			name: "check negated segments operator when target is not in segment",
+			fields: fields{
+				query: testRepo,
+			},
+			args: args{
+				clause: &rest.Clause{
+					Op:     segmentMatchOperator,
+					Negate: true,
+					Values: []string{beta},
+				},
+				target: &Target{
+					Identifier: "no_identifier",
+				},
+			},
 			want: true,
 		},
+		{
+			name:   "negated wrong operator should return false",
+			fields: fields{},
+			args: args{
+				clause: &rest.Clause{
+					Attribute: identifier,
+					Op:        "greaterthan",
+					Negate:    true,
+					Values:    []string{harness},
+				},
+				target: &Target{
+					Identifier: harness,
+				},
+			},
+			want: false,
+		},
+		{
+			name:   "negated clause with no values should return false",
+			fields: fields{},
+			args: args{
+				clause: &rest.Clause{
+					Attribute: identifier,
+					Op:        inOperator,
+					Negate:    true,
+					Values:    []string{},
+				},
+				target: &Target{
+					Identifier: harness,
+				},
+			},
+			want: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -972,7 +1663,7 @@ func TestEvaluator_evaluateRules(t *testing.T) {
 				query:  tt.fields.query,
 				logger: logger.NewNoOpLogger(),
 			}
-			if got := e.evaluateRules(tt.args.servingRules, tt.args.target); got != tt.want {
+			if got, _ := e.evaluateRules(tt.args.servingRules, tt.args.target); got != tt.want {
 				t.Errorf("Evaluator.evaluateRules() = %v, want %v", got, tt.want)
 			}
 		})
@@ -1257,7 +1948,7 @@ func TestEvaluator_evaluateFlag(t *testing.T) {
 				query:  tt.fields.query,
 				logger: logger.NewNoOpLogger(),
 			}
-			got, err := e.evaluateFlag(tt.args.fc, tt.args.target)
+			got, _, err := e.evaluateFlag(tt.args.fc, tt.args.target)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Evaluator.evaluateFlag() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -1986,6 +2677,362 @@ func TestEvaluator_JSONVariation(t *testing.T) {
 	}
 }
 
+func TestEvaluator_BoolVariationDetail(t *testing.T) {
+	ruleID := "rule1"
+	detailRepo := NewTestRepository(
+		map[string]rest.FeatureConfig{
+			"off": {
+				Feature:      "off",
+				State:        rest.FeatureStateOff,
+				OffVariation: identifierFalse,
+				Variations:   boolVariations,
+			},
+			"targetMatch": {
+				Feature:      "targetMatch",
+				State:        rest.FeatureStateOn,
+				OffVariation: identifierFalse,
+				DefaultServe: rest.Serve{Variation: &identifierFalse},
+				VariationToTargetMap: &[]rest.VariationMap{
+					{Variation: identifierTrue, Targets: &[]rest.TargetMap{{Identifier: harness}}},
+				},
+				Variations: boolVariations,
+			},
+			"ruleMatch": {
+				Feature:      "ruleMatch",
+				State:        rest.FeatureStateOn,
+				OffVariation: identifierFalse,
+				DefaultServe: rest.Serve{Variation: &identifierFalse},
+				Rules: &[]rest.ServingRule{
+					{
+						RuleId:  &ruleID,
+						Clauses: []rest.Clause{{Attribute: identifier, Op: equalOperator, Values: []string{harness}}},
+						Serve:   rest.Serve{Variation: &identifierTrue},
+					},
+				},
+				Variations: boolVariations,
+			},
+			"rollout": {
+				Feature:      "rollout",
+				State:        rest.FeatureStateOn,
+				OffVariation: identifierFalse,
+				DefaultServe: rest.Serve{
+					Distribution: &rest.Distribution{
+						BucketBy:   identifier,
+						Variations: []rest.WeightedVariation{{Variation: identifierTrue, Weight: 100}},
+					},
+				},
+				Variations: boolVariations,
+			},
+			"default": {
+				Feature:      "default",
+				State:        rest.FeatureStateOn,
+				OffVariation: identifierFalse,
+				DefaultServe: rest.Serve{Variation: &identifierTrue},
+				Variations:   boolVariations,
+			},
+			"prereqFailed": {
+				Feature:      "prereqFailed",
+				State:        rest.FeatureStateOn,
+				OffVariation: identifierFalse,
+				DefaultServe: rest.Serve{Variation: &identifierTrue},
+				Variations:   boolVariations,
+				Prerequisites: &[]rest.Prerequisite{
+					{Feature: "off", Variations: []string{identifierTrue}},
+				},
+			},
+			"missingVariation": {
+				Feature:      "missingVariation",
+				State:        rest.FeatureStateOn,
+				DefaultServe: rest.Serve{Variation: &darktheme},
+				Variations:   boolVariations,
+			},
+		},
+		map[string]rest.Segment{},
+	)
+
+	tests := []struct {
+		name      string
+		flag      string
+		want      bool
+		wantVar   string
+		wantKind  ReasonKind
+		wantRule  string
+		wantError ErrorKind
+		wantErr   bool
+	}{
+		{name: "flag off", flag: "off", want: false, wantVar: identifierFalse, wantKind: ReasonFlagOff},
+		{name: "target map match", flag: "targetMatch", want: true, wantVar: identifierTrue, wantKind: ReasonTargetMatch},
+		{name: "rule match reports rule id", flag: "ruleMatch", want: true, wantVar: identifierTrue, wantKind: ReasonRuleMatch, wantRule: ruleID},
+		{name: "percentage rollout", flag: "rollout", want: true, wantVar: identifierTrue, wantKind: ReasonPercentageRollout},
+		{name: "default serve", flag: "default", want: true, wantVar: identifierTrue, wantKind: ReasonDefaultServe},
+		{name: "prerequisite failed serves off variation", flag: "prereqFailed", want: false, wantVar: identifierFalse, wantKind: ReasonPrerequisiteFailed},
+		{name: "flag not found", flag: "flagNotFound1000", want: true, wantKind: ReasonError, wantError: ErrorKindFlagNotFound, wantErr: true},
+		{name: "variation not found", flag: "missingVariation", want: true, wantKind: ReasonError, wantError: ErrorKindVariationNotFound, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := Evaluator{
+				query:  detailRepo,
+				logger: logger.NewNoOpLogger(),
+			}
+			got, err := e.BoolVariationDetail(tt.flag, &Target{Identifier: harness}, true)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("BoolVariationDetail() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got.Value != tt.want {
+				t.Errorf("BoolVariationDetail() value = %v, want %v", got.Value, tt.want)
+			}
+			if got.Variation.Identifier != tt.wantVar {
+				t.Errorf("BoolVariationDetail() variation = %v, want %v", got.Variation.Identifier, tt.wantVar)
+			}
+			want := Reason{Kind: tt.wantKind, RuleID: tt.wantRule, ErrorKind: tt.wantError}
+			if got.Reason != want {
+				t.Errorf("BoolVariationDetail() reason = %+v, want %+v", got.Reason, want)
+			}
+		})
+	}
+}
+
+func TestEvaluator_IntVariationDetail_WrongType(t *testing.T) {
+	e := Evaluator{
+		query:  testRepo,
+		logger: logger.NewNoOpLogger(),
+	}
+	got, err := e.IntVariationDetail(invalidInt, nil, 10)
+	if err == nil {
+		t.Errorf("IntVariationDetail() expected error for invalid int variation")
+	}
+	if got.Value != 10 {
+		t.Errorf("IntVariationDetail() value = %v, want default 10", got.Value)
+	}
+	if got.Reason.Kind != ReasonError || got.Reason.ErrorKind != ErrorKindWrongType {
+		t.Errorf("IntVariationDetail() reason = %+v, want wrong type error", got.Reason)
+	}
+}
+
+func TestEvaluator_evaluateClause_ListAttributes(t *testing.T) {
+	e := Evaluator{
+		query:  testRepo,
+		logger: logger.NewNoOpLogger(),
+	}
+	target := &Target{
+		Identifier: harness,
+		Attributes: &map[string]interface{}{
+			"roles":    []string{"admin", "beta"},
+			"groups":   []interface{}{"Engineering", 42},
+			"scores":   []int{10, 75},
+			"versions": []interface{}{"1.2.0", "not-a-version"},
+			"empty":    []string{},
+		},
+	}
+	tests := []struct {
+		name   string
+		clause *rest.Clause
+		want   bool
+	}{
+		{
+			name:   "in matches any element",
+			clause: &rest.Clause{Attribute: "roles", Op: inOperator, Values: []string{"beta", "qa"}},
+			want:   true,
+		},
+		{
+			name:   "in matches no element",
+			clause: &rest.Clause{Attribute: "roles", Op: inOperator, Values: []string{"qa"}},
+			want:   false,
+		},
+		{
+			name:   "negated in matches when no element matches",
+			clause: &rest.Clause{Attribute: "roles", Op: inOperator, Values: []string{"qa"}, Negate: true},
+			want:   true,
+		},
+		{
+			name:   "negated in doesn't match when any element matches",
+			clause: &rest.Clause{Attribute: "roles", Op: inOperator, Values: []string{"admin"}, Negate: true},
+			want:   false,
+		},
+		{
+			name:   "equal is case insensitive per element",
+			clause: &rest.Clause{Attribute: "groups", Op: equalOperator, Values: []string{"engineering"}},
+			want:   true,
+		},
+		{
+			name:   "contains checks each element rather than the stringified list",
+			clause: &rest.Clause{Attribute: "roles", Op: containsOperator, Values: []string{"n b"}},
+			want:   false,
+		},
+		{
+			name:   "starts_with matches an element",
+			clause: &rest.Clause{Attribute: "roles", Op: startsWithOperator, Values: []string{"adm"}},
+			want:   true,
+		},
+		{
+			name:   "mixed element types",
+			clause: &rest.Clause{Attribute: "groups", Op: inOperator, Values: []string{"42"}},
+			want:   true,
+		},
+		{
+			name:   "numeric comparison on typed slice",
+			clause: &rest.Clause{Attribute: "scores", Op: gtOperator, Values: []string{"50"}},
+			want:   true,
+		},
+		{
+			name:   "invalid element is skipped when another matches",
+			clause: &rest.Clause{Attribute: "versions", Op: semverGtOperator, Values: []string{"1.0.0"}},
+			want:   true,
+		},
+		{
+			name:   "invalid element prevents a negated match",
+			clause: &rest.Clause{Attribute: "versions", Op: semverGtOperator, Values: []string{"2.0.0"}, Negate: true},
+			want:   false,
+		},
+		{
+			name:   "empty list doesn't match",
+			clause: &rest.Clause{Attribute: "empty", Op: inOperator, Values: []string{"admin"}},
+			want:   false,
+		},
+		{
+			name:   "nested path into list",
+			clause: &rest.Clause{Attribute: "roles.1", Op: equalOperator, Values: []string{"beta"}},
+			want:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := e.evaluateClause(tt.clause, target); got != tt.want {
+				t.Errorf("evaluateClause() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type regexQuery struct {
+	Query
+	regexes map[string]*regexp.Regexp
+	calls   int
+}
+
+func (q *regexQuery) GetRegex(pattern string) (*regexp.Regexp, bool) {
+	q.calls++
+	re, ok := q.regexes[pattern]
+	return re, ok
+}
+
+func TestEvaluator_evaluateClause_PrecompiledRegex(t *testing.T) {
+	query := &regexQuery{
+		Query: testRepo,
+		regexes: map[string]*regexp.Regexp{
+			"^har": regexp.MustCompile("^har"),
+			"[a-":  nil,
+		},
+	}
+	e := Evaluator{
+		query:  query,
+		logger: logger.NewNoOpLogger(),
+	}
+	target := &Target{Identifier: harness}
+	tests := []struct {
+		name    string
+		pattern string
+		want    bool
+	}{
+		{name: "precompiled pattern matches", pattern: "^har", want: true},
+		{name: "invalid pattern doesn't match", pattern: "[a-", want: false},
+		{name: "unknown pattern is compiled on demand", pattern: "ness$", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clause := &rest.Clause{Attribute: identifier, Op: matchOperator, Values: []string{tt.pattern}}
+			if got := e.evaluateClause(clause, target); got != tt.want {
+				t.Errorf("evaluateClause() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if query.calls != len(tests) {
+		t.Errorf("GetRegex() called %d times, want %d", query.calls, len(tests))
+	}
+}
+
+func TestEvaluator_evaluateClause_CustomOperator(t *testing.T) {
+	hasPrefix := func(attrValue interface{}, values []string) bool {
+		s, ok := attrValue.(string)
+		return ok && len(s) >= len(values[0]) && s[:len(values[0])] == values[0]
+	}
+	isAdult := func(attrValue interface{}, values []string) bool {
+		age, ok := attrValue.(int)
+		return ok && age >= 18
+	}
+	panics := func(attrValue interface{}, values []string) bool {
+		panic("boom")
+	}
+	e := Evaluator{
+		query:  testRepo,
+		logger: logger.NewNoOpLogger(),
+		customOperators: map[string]CustomOperator{
+			"has_prefix": hasPrefix,
+			"is_adult":   isAdult,
+			"panics":     panics,
+			// built in operators take precedence over custom ones
+			equalOperator: func(interface{}, []string) bool { return false },
+		},
+	}
+	target := &Target{
+		Identifier: harness,
+		Attributes: &map[string]interface{}{
+			"ip":  "10.0.0.1",
+			"age": 21,
+		},
+	}
+	tests := []struct {
+		name   string
+		clause *rest.Clause
+		want   bool
+	}{
+		{
+			name:   "custom operator matches",
+			clause: &rest.Clause{Attribute: "ip", Op: "has_prefix", Values: []string{"10.0."}},
+			want:   true,
+		},
+		{
+			name:   "custom operator doesn't match",
+			clause: &rest.Clause{Attribute: "ip", Op: "has_prefix", Values: []string{"192.168."}},
+			want:   false,
+		},
+		{
+			name:   "custom operator receives raw attribute value",
+			clause: &rest.Clause{Attribute: "age", Op: "is_adult", Values: []string{"true"}},
+			want:   true,
+		},
+		{
+			name:   "custom operator is negated",
+			clause: &rest.Clause{Attribute: "ip", Op: "has_prefix", Values: []string{"192.168."}, Negate: true},
+			want:   true,
+		},
+		{
+			name:   "panicking custom operator doesn't match even when negated",
+			clause: &rest.Clause{Attribute: "ip", Op: "panics", Values: []string{"x"}, Negate: true},
+			want:   false,
+		},
+		{
+			name:   "unregistered operator doesn't match",
+			clause: &rest.Clause{Attribute: "ip", Op: "in_cidr", Values: []string{"10.0.0.0/8"}},
+			want:   false,
+		},
+		{
+			name:   "built in operator isn't overridden",
+			clause: &rest.Clause{Attribute: "identifier", Op: equalOperator, Values: []string{harness}},
+			want:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := e.evaluateClause(tt.clause, target); got != tt.want {
+				t.Errorf("evaluateClause() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 // BENCHMARK
 func BenchmarkEvaluateClause_NilClause(b *testing.B) {
 	evaluator := Evaluator{logger: logger.NoOpLogger{}}
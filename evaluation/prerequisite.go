@@ -0,0 +1,80 @@
+package evaluation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/harness/ff-golang-server-sdk/rest"
+)
+
+// maxPrerequisiteDepth caps how deep a chain of prerequisite flags may go
+const maxPrerequisiteDepth = 10
+
+// checkPrerequisitePath returns an error if following the prerequisite to next from the flags in
+// path would form a cycle or exceed the maximum depth
+func checkPrerequisitePath(path []string, next string) error {
+	if contains(path, next) {
+		return fmt.Errorf("%w: %s -> %s", ErrPrerequisiteCycle, strings.Join(path, " -> "), next)
+	}
+	if len(path) > maxPrerequisiteDepth {
+		return fmt.Errorf("%w: %s -> %s exceeds %d levels", ErrPrerequisiteDepthExceeded,
+			strings.Join(path, " -> "), next, maxPrerequisiteDepth)
+	}
+	return nil
+}
+
+// appendPath returns a copy of path with identifier added, so that sibling prerequisites don't share
+// a backing array
+func appendPath(path []string, identifier string) []string {
+	next := make([]string, len(path), len(path)+1)
+	copy(next, path)
+	return append(next, identifier)
+}
+
+// ValidatePrerequisites walks the prerequisite graph of each flag and returns an error for every flag
+// that is part of a prerequisite cycle or whose prerequisite chain is too deep. Prerequisites that
+// aren't in flags are resolved using getFlag, and any that can't be found are skipped.
+func ValidatePrerequisites(getFlag func(identifier string) (rest.FeatureConfig, error), flags ...rest.FeatureConfig) []error {
+	loaded := make(map[string]rest.FeatureConfig, len(flags))
+	for _, flag := range flags {
+		loaded[flag.Feature] = flag
+	}
+	lookup := func(identifier string) (rest.FeatureConfig, bool) {
+		if flag, ok := loaded[identifier]; ok {
+			return flag, true
+		}
+		if getFlag == nil {
+			return rest.FeatureConfig{}, false
+		}
+		flag, err := getFlag(identifier)
+		return flag, err == nil
+	}
+
+	var errs []error
+	for _, flag := range flags {
+		if err := validatePrerequisitePath(flag, []string{flag.Feature}, lookup); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+func validatePrerequisitePath(flag rest.FeatureConfig, path []string,
+	lookup func(identifier string) (rest.FeatureConfig, bool)) error {
+	if flag.Prerequisites == nil {
+		return nil
+	}
+	for _, pre := range *flag.Prerequisites {
+		if err := checkPrerequisitePath(path, pre.Feature); err != nil {
+			return err
+		}
+		prereqFlag, ok := lookup(pre.Feature)
+		if !ok {
+			continue
+		}
+		if err := validatePrerequisitePath(prereqFlag, appendPath(path, pre.Feature), lookup); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package evaluation
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/harness/ff-golang-server-sdk/logger"
+	"github.com/harness/ff-golang-server-sdk/rest"
+)
+
+func prereqFlag(identifier string, prerequisites ...string) rest.FeatureConfig {
+	var prereqs []rest.Prerequisite
+	for _, p := range prerequisites {
+		prereqs = append(prereqs, rest.Prerequisite{Feature: p, Variations: []string{"true"}})
+	}
+	on := "true"
+	return rest.FeatureConfig{
+		Feature:      identifier,
+		Kind:         "boolean",
+		State:        rest.FeatureStateOn,
+		OffVariation: "false",
+		DefaultServe: rest.Serve{Variation: &on},
+		Variations: []rest.Variation{
+			{Identifier: "true", Value: "true"},
+			{Identifier: "false", Value: "false"},
+		},
+		Prerequisites: &prereqs,
+	}
+}
+
+// chain returns flags f0 -> f1 -> ... -> f<n>
+func chain(n int) []rest.FeatureConfig {
+	flags := make([]rest.FeatureConfig, 0, n+1)
+	for i := 0; i < n; i++ {
+		flags = append(flags, prereqFlag(fmt.Sprintf("f%d", i), fmt.Sprintf("f%d", i+1)))
+	}
+	return append(flags, prereqFlag(fmt.Sprintf("f%d", n)))
+}
+
+func flagMap(flags ...rest.FeatureConfig) map[string]rest.FeatureConfig {
+	m := map[string]rest.FeatureConfig{}
+	for _, f := range flags {
+		m[f.Feature] = f
+	}
+	return m
+}
+
+func TestValidatePrerequisites(t *testing.T) {
+	tests := []struct {
+		name    string
+		flags   []rest.FeatureConfig
+		wantErr []error
+	}{
+		{
+			name:  "no cycle",
+			flags: []rest.FeatureConfig{prereqFlag("a", "b", "c"), prereqFlag("b", "c"), prereqFlag("c")},
+		},
+		{
+			name:    "self cycle",
+			flags:   []rest.FeatureConfig{prereqFlag("a", "a")},
+			wantErr: []error{ErrPrerequisiteCycle},
+		},
+		{
+			name:    "two flag cycle reports both flags",
+			flags:   []rest.FeatureConfig{prereqFlag("a", "b"), prereqFlag("b", "a")},
+			wantErr: []error{ErrPrerequisiteCycle, ErrPrerequisiteCycle},
+		},
+		{
+			name:  "diamond isn't a cycle",
+			flags: []rest.FeatureConfig{prereqFlag("a", "b", "c"), prereqFlag("b", "d"), prereqFlag("c", "d"), prereqFlag("d")},
+		},
+		{
+			name:  "missing prerequisite is skipped",
+			flags: []rest.FeatureConfig{prereqFlag("a", "missing")},
+		},
+		{
+			name:  "chain at max depth",
+			flags: chain(maxPrerequisiteDepth),
+		},
+		{
+			name:    "chain beyond max depth",
+			flags:   chain(maxPrerequisiteDepth + 1),
+			wantErr: []error{ErrPrerequisiteDepthExceeded},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := ValidatePrerequisites(nil, tt.flags...)
+			if len(errs) != len(tt.wantErr) {
+				t.Fatalf("ValidatePrerequisites() = %v, want %v", errs, tt.wantErr)
+			}
+			for i, err := range errs {
+				if !errors.Is(err, tt.wantErr[i]) {
+					t.Errorf("ValidatePrerequisites()[%d] = %v, want %v", i, err, tt.wantErr[i])
+				}
+			}
+		})
+	}
+}
+
+func TestEvaluator_PrerequisiteCycle(t *testing.T) {
+	tests := []struct {
+		name      string
+		flags     []rest.FeatureConfig
+		flag      string
+		wantValue bool
+		wantKind  ErrorKind
+	}{
+		{
+			name:      "valid prerequisite serves default",
+			flags:     []rest.FeatureConfig{prereqFlag("a", "b"), prereqFlag("b")},
+			flag:      "a",
+			wantValue: true,
+			wantKind:  ErrorKindNone,
+		},
+		{
+			name:      "cycle serves off variation",
+			flags:     []rest.FeatureConfig{prereqFlag("a", "b"), prereqFlag("b", "a")},
+			flag:      "a",
+			wantValue: false,
+			wantKind:  ErrorKindPrerequisiteCycle,
+		},
+		{
+			name:      "too deep serves off variation",
+			flags:     chain(maxPrerequisiteDepth + 1),
+			flag:      "f0",
+			wantValue: false,
+			wantKind:  ErrorKindPrerequisiteDepthExceeded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := Evaluator{
+				query:  NewTestRepository(flagMap(tt.flags...), nil),
+				logger: logger.NewNoOpLogger(),
+			}
+			got, err := e.BoolVariationDetail(tt.flag, &Target{Identifier: harness}, true)
+			if err != nil {
+				t.Fatalf("BoolVariationDetail() error = %v", err)
+			}
+			if got.Value != tt.wantValue {
+				t.Errorf("BoolVariationDetail() value = %v, want %v", got.Value, tt.wantValue)
+			}
+			if got.Reason.ErrorKind != tt.wantKind {
+				t.Errorf("BoolVariationDetail() error kind = %v, want %v", got.Reason.ErrorKind, tt.wantKind)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package evaluation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// semanticVersion is a parsed https://semver.org/ version. Build metadata is validated but
+// dropped as it has no bearing on precedence.
+type semanticVersion struct {
+	major      uint64
+	minor      uint64
+	patch      uint64
+	preRelease []string
+}
+
+// parseSemver parses versions of the form [v]MAJOR[.MINOR[.PATCH]][-PRERELEASE][+BUILD].
+// Missing minor and patch numbers are treated as zero, so app versions like "2.1" can be compared.
+func parseSemver(version string) (semanticVersion, error) {
+	v := strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if v == "" {
+		return semanticVersion{}, fmt.Errorf("%w: empty version", ErrInvalidSemver)
+	}
+
+	if i := strings.IndexByte(v, '+'); i >= 0 {
+		if !validIdentifiers(v[i+1:], false) {
+			return semanticVersion{}, fmt.Errorf("%w: invalid build metadata in %q", ErrInvalidSemver, version)
+		}
+		v = v[:i]
+	}
+
+	var sv semanticVersion
+	if i := strings.IndexByte(v, '-'); i >= 0 {
+		pre := v[i+1:]
+		if !validIdentifiers(pre, true) {
+			return semanticVersion{}, fmt.Errorf("%w: invalid pre-release in %q", ErrInvalidSemver, version)
+		}
+		sv.preRelease = strings.Split(pre, ".")
+		v = v[:i]
+	}
+
+	parts := strings.Split(v, ".")
+	if len(parts) > 3 {
+		return semanticVersion{}, fmt.Errorf("%w: too many version numbers in %q", ErrInvalidSemver, version)
+	}
+	numbers := []*uint64{&sv.major, &sv.minor, &sv.patch}
+	for i, part := range parts {
+		n, err := parseVersionNumber(part)
+		if err != nil {
+			return semanticVersion{}, fmt.Errorf("%w: %q", ErrInvalidSemver, version)
+		}
+		*numbers[i] = n
+	}
+	return sv, nil
+}
+
+// parseVersionNumber parses a numeric version component, rejecting signs and leading zeros
+func parseVersionNumber(s string) (uint64, error) {
+	if s == "" || (len(s) > 1 && s[0] == '0') {
+		return 0, ErrInvalidSemver
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return 0, ErrInvalidSemver
+		}
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+// validIdentifiers checks a dot separated list of identifiers only contains [0-9A-Za-z-].
+// Numeric pre-release identifiers must not have leading zeros.
+func validIdentifiers(s string, preRelease bool) bool {
+	if s == "" {
+		return false
+	}
+	for _, id := range strings.Split(s, ".") {
+		if id == "" {
+			return false
+		}
+		for _, r := range id {
+			if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '-') {
+				return false
+			}
+		}
+		if preRelease && isNumeric(id) && len(id) > 1 && id[0] == '0' {
+			return false
+		}
+	}
+	return true
+}
+
+func isNumeric(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// compare returns -1, 0 or 1 if sv has lower, equal or higher precedence than other
+func (sv semanticVersion) compare(other semanticVersion) int {
+	if c := compareUint(sv.major, other.major); c != 0 {
+		return c
+	}
+	if c := compareUint(sv.minor, other.minor); c != 0 {
+		return c
+	}
+	if c := compareUint(sv.patch, other.patch); c != 0 {
+		return c
+	}
+
+	// A version without a pre-release has higher precedence than one with
+	switch {
+	case len(sv.preRelease) == 0 && len(other.preRelease) == 0:
+		return 0
+	case len(sv.preRelease) == 0:
+		return 1
+	case len(other.preRelease) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(sv.preRelease) && i < len(other.preRelease); i++ {
+		if c := comparePreReleaseIdentifier(sv.preRelease[i], other.preRelease[i]); c != 0 {
+			return c
+		}
+	}
+	return compareUint(uint64(len(sv.preRelease)), uint64(len(other.preRelease)))
+}
+
+// comparePreReleaseIdentifier compares numeric identifiers numerically and others lexically,
+// numeric identifiers always have lower precedence than alphanumeric ones
+func comparePreReleaseIdentifier(a, b string) int {
+	aNum, bNum := isNumeric(a), isNumeric(b)
+	switch {
+	case aNum && bNum:
+		an, _ := strconv.ParseUint(a, 10, 64)
+		bn, _ := strconv.ParseUint(b, 10, 64)
+		return compareUint(an, bn)
+	case aNum:
+		return -1
+	case bNum:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compareSemver compares the attribute version with the clause version, an error is returned
+// if either isn't a valid semantic version
+func compareSemver(attrValue string, value string) (int, error) {
+	attrVersion, err := parseSemver(attrValue)
+	if err != nil {
+		return 0, err
+	}
+	clauseVersion, err := parseSemver(value)
+	if err != nil {
+		return 0, err
+	}
+	return attrVersion.compare(clauseVersion), nil
+}
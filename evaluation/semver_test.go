@@ -0,0 +1,84 @@
+package evaluation
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_parseSemver(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		want    semanticVersion
+		wantErr bool
+	}{
+		{name: "full version", version: "1.2.3", want: semanticVersion{major: 1, minor: 2, patch: 3}},
+		{name: "v prefix", version: "v10.20.30", want: semanticVersion{major: 10, minor: 20, patch: 30}},
+		{name: "missing patch", version: "2.1", want: semanticVersion{major: 2, minor: 1}},
+		{name: "major only", version: "3", want: semanticVersion{major: 3}},
+		{name: "pre-release", version: "1.0.0-alpha.1", want: semanticVersion{major: 1, preRelease: []string{"alpha", "1"}}},
+		{name: "build metadata is ignored", version: "1.0.0+20130313144700", want: semanticVersion{major: 1}},
+		{name: "pre-release and build", version: "1.0.0-beta+exp.sha.5114f85", want: semanticVersion{major: 1, preRelease: []string{"beta"}}},
+		{name: "empty", version: "", wantErr: true},
+		{name: "not a number", version: "one.two.three", wantErr: true},
+		{name: "too many parts", version: "1.2.3.4", wantErr: true},
+		{name: "leading zero", version: "01.2.3", wantErr: true},
+		{name: "negative", version: "-1.2.3", wantErr: true},
+		{name: "empty pre-release", version: "1.2.3-", wantErr: true},
+		{name: "empty pre-release identifier", version: "1.2.3-alpha..1", wantErr: true},
+		{name: "pre-release numeric leading zero", version: "1.2.3-01", wantErr: true},
+		{name: "invalid build characters", version: "1.2.3+build!", wantErr: true},
+		{name: "trailing dot", version: "1.2.", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSemver(tt.version)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSemver(%q) error = %v, wantErr %v", tt.version, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidSemver) {
+					t.Errorf("parseSemver(%q) error = %v, want ErrInvalidSemver", tt.version, err)
+				}
+				return
+			}
+			if got.compare(tt.want) != 0 || len(got.preRelease) != len(tt.want.preRelease) {
+				t.Errorf("parseSemver(%q) = %+v, want %+v", tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_compareSemver(t *testing.T) {
+	// precedence examples from https://semver.org/#spec-item-11
+	ordered := []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1",
+		"1.1.0",
+		"2.0.0",
+		"10.0.0",
+	}
+	for i := 0; i < len(ordered)-1; i++ {
+		lower, higher := ordered[i], ordered[i+1]
+		if got, err := compareSemver(lower, higher); err != nil || got != -1 {
+			t.Errorf("compareSemver(%q, %q) = %d, %v, want -1", lower, higher, got, err)
+		}
+		if got, err := compareSemver(higher, lower); err != nil || got != 1 {
+			t.Errorf("compareSemver(%q, %q) = %d, %v, want 1", higher, lower, got, err)
+		}
+	}
+
+	if got, err := compareSemver("1.2.0+build.1", "v1.2"); err != nil || got != 0 {
+		t.Errorf("compareSemver() = %d, %v, want versions differing only by build to be equal", got, err)
+	}
+	if _, err := compareSemver("1.2.3", "latest"); err == nil {
+		t.Errorf("compareSemver() expected error for malformed clause version")
+	}
+}
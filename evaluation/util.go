@@ -2,8 +2,10 @@ package evaluation
 
 import (
 	"fmt"
+	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	jsoniter "github.com/json-iterator/go"
 
@@ -11,44 +13,206 @@ import (
 
 	"github.com/harness/ff-golang-server-sdk/log"
 	"github.com/harness/ff-golang-server-sdk/rest"
+	"github.com/harness/ff-golang-server-sdk/types"
 	"github.com/spaolacci/murmur3"
 )
 
-func getAttrValue(target *Target, attr string) string {
+// getRawAttrValue returns the untyped value of the target attribute, and whether it was found.
+// Besides top level attributes, attr may be a dotted path e.g. "org.plan" or a JSON pointer
+// e.g. "/device/os" that resolves into nested maps and slices. A top level attribute whose name
+// contains dots takes precedence over the dotted path.
+func getRawAttrValue(target *Target, attr string) (interface{}, bool) {
 	if target == nil || attr == "" {
-		return ""
+		return nil, false
 	}
 
 	switch attr {
 	case "identifier":
-		return target.Identifier
+		return target.Identifier, true
 	case "name":
-		return target.Name
+		return target.Name, true
 	default:
-		if target.Attributes != nil {
-			if val, ok := (*target.Attributes)[attr]; ok {
-				switch v := val.(type) {
-				case string:
-					return v
-				case int:
-					return strconv.Itoa(v)
-				case float64:
-					return strconv.FormatFloat(v, 'f', -1, 64)
-				case bool:
-					return strconv.FormatBool(v)
-				case map[string]interface{}:
-					marshalledValue, err := jsoniter.MarshalToString(v)
-					if err != nil {
-						return fmt.Sprint(v)
-					}
-					return marshalledValue
-				default:
-					return fmt.Sprint(v)
-				}
+		if target.Attributes == nil {
+			return nil, false
+		}
+		if val, ok := (*target.Attributes)[attr]; ok {
+			return val, true
+		}
+		if path, ok := attrPath(attr); ok {
+			return lookupPath(*target.Attributes, path)
+		}
+	}
+	return nil, false
+}
+
+// attrPath splits a JSON pointer or dotted attribute into its path segments, it returns false
+// if attr is a plain attribute name
+func attrPath(attr string) ([]string, bool) {
+	if strings.HasPrefix(attr, "/") {
+		path := strings.Split(attr[1:], "/")
+		for i, segment := range path {
+			// RFC 6901 escaping, ~1 must be replaced before ~0
+			path[i] = strings.ReplaceAll(strings.ReplaceAll(segment, "~1", "/"), "~0", "~")
+		}
+		return path, true
+	}
+	if strings.Contains(attr, ".") {
+		return strings.Split(attr, "."), true
+	}
+	return nil, false
+}
+
+// lookupPath walks the path through nested maps and slices, slice elements are addressed by index
+func lookupPath(attributes map[string]interface{}, path []string) (interface{}, bool) {
+	var current interface{} = attributes
+	for _, segment := range path {
+		switch v := current.(type) {
+		case map[string]interface{}:
+			val, ok := v[segment]
+			if !ok {
+				return nil, false
+			}
+			current = val
+		case []interface{}:
+			i, err := strconv.Atoi(segment)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			current = v[i]
+		default:
+			val, ok := lookupReflect(current, segment)
+			if !ok {
+				return nil, false
 			}
+			current = val
 		}
 	}
-	return ""
+	return current, true
+}
+
+// lookupReflect handles typed maps and slices e.g. map[string]string or []string, which aren't
+// produced by decoding JSON but are common when targets are built in code
+func lookupReflect(value interface{}, segment string) (interface{}, bool) {
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		val := rv.MapIndex(reflect.ValueOf(segment).Convert(rv.Type().Key()))
+		if !val.IsValid() {
+			return nil, false
+		}
+		return val.Interface(), true
+	case reflect.Slice, reflect.Array:
+		i, err := strconv.Atoi(segment)
+		if err != nil || i < 0 || i >= rv.Len() {
+			return nil, false
+		}
+		return rv.Index(i).Interface(), true
+	}
+	return nil, false
+}
+
+func getAttrValue(target *Target, attr string) string {
+	val, ok := getRawAttrValue(target, attr)
+	if !ok {
+		return ""
+	}
+	return attrValueToString(val)
+}
+
+func attrValueToString(val interface{}) string {
+	switch v := val.(type) {
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case map[string]interface{}:
+		marshalledValue, err := jsoniter.MarshalToString(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return marshalledValue
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// attrOperand returns the attribute value as a typed value, so that comparison operators
+// compare numeric attributes numerically rather than lexicographically. Strings holding a number
+// are treated as numbers as well, since attributes often arrive as strings from HTTP headers or JWTs.
+func attrOperand(val interface{}) types.ValueType {
+	if val == nil {
+		return nil
+	}
+
+	switch v := val.(type) {
+	case int:
+		return types.Number(v)
+	case int8:
+		return types.Number(v)
+	case int16:
+		return types.Number(v)
+	case int32:
+		return types.Number(v)
+	case int64:
+		return types.Number(v)
+	case uint:
+		return types.Number(v)
+	case uint8:
+		return types.Number(v)
+	case uint16:
+		return types.Number(v)
+	case uint32:
+		return types.Number(v)
+	case uint64:
+		return types.Number(v)
+	case float32:
+		return types.Number(v)
+	case float64:
+		return types.Number(v)
+	case string:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return types.Number(f)
+		}
+		return types.String(v)
+	default:
+		return types.String(attrValueToString(v))
+	}
+}
+
+// sliceElements returns the elements of a list attribute e.g. roles: ["admin", "beta"], and false
+// if the value isn't a list. Byte slices are treated as scalar values.
+func sliceElements(val interface{}) ([]interface{}, bool) {
+	switch v := val.(type) {
+	case []interface{}:
+		return v, true
+	case []string:
+		elements := make([]interface{}, len(v))
+		for i, s := range v {
+			elements[i] = s
+		}
+		return elements, true
+	case []byte:
+		return nil, false
+	}
+
+	rv := reflect.ValueOf(val)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false
+	}
+	elements := make([]interface{}, rv.Len())
+	for i := range elements {
+		elements[i] = rv.Index(i).Interface()
+	}
+	return elements, true
 }
 
 func findVariation(variations []rest.Variation, identifier string) (rest.Variation, error) {
@@ -3,6 +3,7 @@ package evaluation
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/harness/ff-golang-server-sdk/rest"
 )
@@ -130,6 +131,19 @@ func Test_getAttrValue(t *testing.T) {
 			},
 			wantStr: "true",
 		},
+		{
+			name: "check time attributes are formatted as RFC3339",
+			args: args{
+				target: &Target{
+					Identifier: "identifier",
+					Attributes: &map[string]interface{}{
+						"createdAt": time.Date(2025, 1, 1, 9, 30, 0, 0, time.UTC),
+					},
+				},
+				attr: "createdAt",
+			},
+			wantStr: "2025-01-01T09:30:00Z",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -141,6 +155,51 @@ func Test_getAttrValue(t *testing.T) {
 	}
 }
 
+func Test_getAttrValue_NestedPaths(t *testing.T) {
+	target := &Target{
+		Identifier: "harness",
+		Attributes: &map[string]interface{}{
+			"org": map[string]interface{}{
+				"plan":  "enterprise",
+				"seats": 250,
+			},
+			"device": map[string]interface{}{
+				"os": "ios",
+			},
+			"roles":      []interface{}{"admin", map[string]interface{}{"name": "billing"}},
+			"tags":       []string{"beta", "internal"},
+			"labels":     map[string]string{"tier": "gold"},
+			"a/b":        map[string]interface{}{"c~d": "escaped"},
+			"dotted.key": "literal",
+		},
+	}
+	tests := []struct {
+		name    string
+		attr    string
+		wantStr string
+	}{
+		{name: "dotted path", attr: "org.plan", wantStr: "enterprise"},
+		{name: "dotted path to number", attr: "org.seats", wantStr: "250"},
+		{name: "json pointer", attr: "/device/os", wantStr: "ios"},
+		{name: "slice index", attr: "roles.0", wantStr: "admin"},
+		{name: "map inside slice", attr: "/roles/1/name", wantStr: "billing"},
+		{name: "typed slice", attr: "tags.1", wantStr: "internal"},
+		{name: "typed map", attr: "labels.tier", wantStr: "gold"},
+		{name: "json pointer escaping", attr: "/a~1b/c~0d", wantStr: "escaped"},
+		{name: "top level key with dots takes precedence", attr: "dotted.key", wantStr: "literal"},
+		{name: "missing key", attr: "org.region", wantStr: ""},
+		{name: "index out of range", attr: "roles.5", wantStr: ""},
+		{name: "path through scalar", attr: "device.os.version", wantStr: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getAttrValue(target, tt.attr); got != tt.wantStr {
+				t.Errorf("getAttrValue() = %v, want %v", got, tt.wantStr)
+			}
+		})
+	}
+}
+
 func Test_findVariation(t *testing.T) {
 	trueVariation := rest.Variation{
 		Identifier: identifierTrue,
@@ -279,6 +338,23 @@ func Test_isEnabled(t *testing.T) {
 	}
 }
 
+func Test_isEnabled_NestedBucketBy(t *testing.T) {
+	target := &Target{
+		Identifier: harness,
+		Attributes: &map[string]interface{}{
+			"org": map[string]interface{}{"id": "acme"},
+		},
+	}
+	// the nested value should be used for bucketing rather than falling back to the identifier
+	bucket := getNormalizedNumber("acme", "org.id")
+	if !isEnabled(target, "org.id", bucket) {
+		t.Errorf("isEnabled() = false, want true at percentage %d", bucket)
+	}
+	if isEnabled(target, "org.id", bucket-1) {
+		t.Errorf("isEnabled() = true, want false at percentage %d", bucket-1)
+	}
+}
+
 func Test_evaluateDistribution(t *testing.T) {
 	type args struct {
 		distribution *rest.Distribution
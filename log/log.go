@@ -15,6 +15,11 @@ func SetLogger(logger logger.Logger) {
 	defaultLogger = logger
 }
 
+// GetLogger returns the default logger used by this package
+func GetLogger() logger.Logger {
+	return defaultLogger
+}
+
 // Error logs an error message with the parameters
 func Error(args ...interface{}) {
 	defaultLogger.Error(args...)
@@ -1,11 +1,13 @@
 package repository
 
 import (
+	"github.com/harness/ff-golang-server-sdk/cache"
 	"github.com/harness/ff-golang-server-sdk/log"
+	"github.com/harness/ff-golang-server-sdk/logger"
 	lru "github.com/hashicorp/golang-lru"
 )
 
-// Cache wrapper to integrate any 3rd party implementation
+// Cache wrapper to integrate any 3rd party implementation, cache.Cache is a Cache
 type Cache interface {
 	Set(key interface{}, value interface{}) (evicted bool)
 	Contains(key interface{}) bool
@@ -21,6 +23,7 @@ type LRUCache struct {
 }
 
 var _ Cache = &LRUCache{}
+var _ Cache = cache.Cache(nil)
 
 // NewLruCache creates a new LRU instance
 func NewLruCache(size int) (LRUCache, error) {
@@ -71,3 +74,9 @@ func (lru LRUCache) Remove(key interface{}) (present bool) {
 	}
 	return
 }
+
+// NewLruCacheWithLogger creates a cache.LRUCache, which unlike LRUCache records when it was last updated
+// so it can be persisted with cache.Persistence
+func NewLruCacheWithLogger(size int, logger logger.Logger) (*cache.LRUCache, error) {
+	return cache.NewLruCache(size, logger)
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+)
+
+// flagIndex tracks the identifiers of the flags held by the repository, so all flags can be listed
+// without depending on the cache or storage being able to enumerate their keys. A flag can be held under
+// its own key by SetFlag, in the list of its environment by SetFlags, or both, so the index records
+// where each flag is held and it's listed until it's been removed from all of them.
+type flagIndex struct {
+	mu          sync.RWMutex
+	individual  map[string]struct{}
+	environment map[string]map[string]struct{}
+}
+
+// indexedFlag is a flag listed by the index and where it's held
+type indexedFlag struct {
+	identifier string
+	// individual is true if the flag is held under its own key
+	individual bool
+	// envIDs are the environments whose list of flags holds the flag
+	envIDs []string
+}
+
+func newFlagIndex() *flagIndex {
+	return &flagIndex{
+		individual:  map[string]struct{}{},
+		environment: map[string]map[string]struct{}{},
+	}
+}
+
+// add records that the flag is held under its own key
+func (i *flagIndex) add(identifier string) {
+	if i == nil {
+		return
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.individual[identifier] = struct{}{}
+}
+
+// remove records that the flag is no longer held under its own key
+func (i *flagIndex) remove(identifier string) {
+	if i == nil {
+		return
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	delete(i.individual, identifier)
+}
+
+// setEnvironment records the flags held in the list of an environment, replacing the previous list
+func (i *flagIndex) setEnvironment(envID string, identifiers ...string) {
+	if i == nil {
+		return
+	}
+	set := make(map[string]struct{}, len(identifiers))
+	for _, identifier := range identifiers {
+		set[identifier] = struct{}{}
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.environment[envID] = set
+}
+
+// removeFromEnvironment records that a flag is no longer held in the list of an environment
+func (i *flagIndex) removeFromEnvironment(envID string, identifier string) {
+	if i == nil {
+		return
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	delete(i.environment[envID], identifier)
+}
+
+// list returns the flags in sorted order
+func (i *flagIndex) list() []indexedFlag {
+	if i == nil {
+		return nil
+	}
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	flags := map[string]*indexedFlag{}
+	get := func(identifier string) *indexedFlag {
+		flag, ok := flags[identifier]
+		if !ok {
+			flag = &indexedFlag{identifier: identifier}
+			flags[identifier] = flag
+		}
+		return flag
+	}
+	for identifier := range i.individual {
+		get(identifier).individual = true
+	}
+	for envID, identifiers := range i.environment {
+		for identifier := range identifiers {
+			flag := get(identifier)
+			flag.envIDs = append(flag.envIDs, envID)
+		}
+	}
+	return sortIndexedFlags(flags)
+}
+
+func sortIndexedFlags(flags map[string]*indexedFlag) []indexedFlag {
+	list := make([]indexedFlag, 0, len(flags))
+	for _, flag := range flags {
+		sort.Strings(flag.envIDs)
+		list = append(list, *flag)
+	}
+	sort.Slice(list, func(a, b int) bool {
+		return list[a].identifier < list[b].identifier
+	})
+	return list
+}
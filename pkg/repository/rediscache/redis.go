@@ -0,0 +1,268 @@
+// Package rediscache provides a repository cache that shares flags and segments between instances of
+// an application through a server that speaks the redis protocol. It's a separate package so that the
+// redis client is only a dependency of applications that use it.
+package rediscache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/harness/ff-golang-server-sdk/log"
+	"github.com/harness/ff-golang-server-sdk/logger"
+	"github.com/harness/ff-golang-server-sdk/pkg/repository"
+)
+
+// defaultRedisPrefix namespaces the keys written to redis
+const defaultRedisPrefix = "harness-ffm:"
+
+// refreshBatchSize is how many keys are read from redis with each MGET during a refresh
+const refreshBatchSize = 100
+
+// RedisCacheOption configures a RedisCache
+type RedisCacheOption func(c *RedisCache)
+
+// WithRedisPrefix sets the prefix of every key written to redis, so several environments can share
+// a server
+func WithRedisPrefix(prefix string) RedisCacheOption {
+	return func(c *RedisCache) {
+		c.prefix = prefix
+	}
+}
+
+// WithRedisTimeout sets how long each redis command made when writing to the cache may take
+func WithRedisTimeout(timeout time.Duration) RedisCacheOption {
+	return func(c *RedisCache) {
+		c.timeout = timeout
+	}
+}
+
+// RedisCache stores flags and segments in a server that speaks the redis protocol, so that several
+// instances of an application can share a single copy of the flag state. Values are stored as JSON
+// and decoded back into the rest types using the prefix of the repository key.
+//
+// Reads are served from an in-process copy so evaluations never wait for redis. Writes update the copy
+// and redis, and Refresh replaces the copy with the keys held in redis, which is how an instance sees
+// the flags written by another one.
+//
+// Redis doesn't evict keys unless it's configured with a maxmemory policy, so the cache has no
+// capacity of its own.
+type RedisCache struct {
+	client  redis.UniversalClient
+	prefix  string
+	timeout time.Duration
+
+	mu         sync.RWMutex
+	values     map[string]interface{}
+	lastUpdate time.Time
+	logger     logger.Logger
+}
+
+var _ repository.RefreshableCache = &RedisCache{}
+
+// NewRedisCache creates a cache that stores flags and segments using client
+func NewRedisCache(client redis.UniversalClient, options ...RedisCacheOption) *RedisCache {
+	c := &RedisCache{
+		client:  client,
+		prefix:  defaultRedisPrefix,
+		timeout: 2 * time.Second,
+		values:  map[string]interface{}{},
+	}
+	for _, opt := range options {
+		opt(c)
+	}
+	return c
+}
+
+func (c *RedisCache) newContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), c.timeout)
+}
+
+func (c *RedisCache) errorf(format string, args ...interface{}) {
+	c.mu.RLock()
+	l := c.logger
+	c.mu.RUnlock()
+	if l != nil {
+		l.Errorf(format, args...)
+		return
+	}
+	log.Errorf(format, args...)
+}
+
+// Set stores value in the in-process copy and the JSON encoding of value in redis. A failure to write to
+// redis is logged, and the value is still served by this instance. Keys are never evicted so it always
+// returns false.
+func (c *RedisCache) Set(key interface{}, value interface{}) (evicted bool) {
+	k := keyString(key)
+	c.mu.Lock()
+	c.values[k] = value
+	c.lastUpdate = time.Now()
+	c.mu.Unlock()
+
+	encoded, err := jsoniter.Marshal(value)
+	if err != nil {
+		c.errorf("error encoding cache value for key %v, err: %v", key, err)
+		return false
+	}
+	ctx, cancel := c.newContext()
+	defer cancel()
+	if err := c.client.Set(ctx, c.prefix+k, encoded, 0).Err(); err != nil {
+		c.errorf("error writing key %v to redis, err: %v", key, err)
+	}
+	return false
+}
+
+// Contains checks if a key is in the cache
+func (c *RedisCache) Contains(key interface{}) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, ok := c.values[keyString(key)]
+	return ok
+}
+
+// Get looks up a key's value
+func (c *RedisCache) Get(key interface{}) (value interface{}, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	value, ok = c.values[keyString(key)]
+	return value, ok
+}
+
+// Keys returns the keys held in the cache, without the prefix
+func (c *RedisCache) Keys() []interface{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	keys := make([]interface{}, 0, len(c.values))
+	for key := range c.values {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Len returns the number of keys held in the cache
+func (c *RedisCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.values)
+}
+
+// Remove removes the provided key from the cache and redis
+func (c *RedisCache) Remove(key interface{}) (present bool) {
+	k := keyString(key)
+	c.mu.Lock()
+	_, present = c.values[k]
+	delete(c.values, k)
+	c.lastUpdate = time.Now()
+	c.mu.Unlock()
+
+	ctx, cancel := c.newContext()
+	defer cancel()
+	if err := c.client.Del(ctx, c.prefix+k).Err(); err != nil {
+		c.errorf("error removing key %v from redis, err: %v", key, err)
+	}
+	return present
+}
+
+// Purge removes every key with the prefix from the cache and redis
+func (c *RedisCache) Purge() {
+	c.mu.Lock()
+	c.values = map[string]interface{}{}
+	c.lastUpdate = time.Now()
+	c.mu.Unlock()
+
+	ctx, cancel := c.newContext()
+	defer cancel()
+	keys, err := c.scan(ctx)
+	if err == nil && len(keys) > 0 {
+		err = c.client.Del(ctx, keys...).Err()
+	}
+	if err != nil {
+		c.errorf("error purging redis, err: %v", err)
+	}
+}
+
+// Refresh replaces the in-process copy with the keys held in redis. The keys are read in batches rather
+// than in a single transaction, so a refresh made while another instance is writing can see some of its
+// writes and not others, as evaluations on that instance would. The copy is left unchanged if redis
+// can't be read.
+func (c *RedisCache) Refresh(ctx context.Context) error {
+	keys, err := c.scan(ctx)
+	if err != nil {
+		return err
+	}
+
+	values := make(map[string]interface{}, len(keys))
+	for start := 0; start < len(keys); start += refreshBatchSize {
+		batch := keys[start:min(start+refreshBatchSize, len(keys))]
+		encoded, err := c.client.MGet(ctx, batch...).Result()
+		if err != nil {
+			return err
+		}
+		for i, value := range encoded {
+			// the key was removed since it was listed
+			s, ok := value.(string)
+			if !ok {
+				continue
+			}
+			key := strings.TrimPrefix(batch[i], c.prefix)
+			decoded, err := repository.DecodeCacheValue(key, []byte(s))
+			if err != nil {
+				c.errorf("error decoding key %v from redis, err: %v", key, err)
+				continue
+			}
+			values[key] = decoded
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values = values
+	c.lastUpdate = time.Now()
+	return nil
+}
+
+// scan lists the keys in redis with the prefix
+func (c *RedisCache) scan(ctx context.Context) ([]string, error) {
+	var keys []string
+	iter := c.client.Scan(ctx, 0, c.prefix+"*", 0).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	return keys, iter.Err()
+}
+
+// Resize is a no-op, the capacity of the cache is managed by the redis server
+func (c *RedisCache) Resize(size int) (evicted int) {
+	return 0
+}
+
+// Updated returns when the cache was last written or refreshed
+func (c *RedisCache) Updated() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.lastUpdate
+}
+
+// SetLogger set logger
+func (c *RedisCache) SetLogger(logger logger.Logger) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.logger = logger
+}
+
+// Shared returns true, the cache can be written by other instances
+func (c *RedisCache) Shared() bool {
+	return true
+}
+
+func keyString(key interface{}) string {
+	if s, ok := key.(string); ok {
+		return s
+	}
+	return fmt.Sprint(key)
+}
@@ -0,0 +1,146 @@
+package rediscache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/harness/ff-golang-server-sdk/cache"
+	"github.com/harness/ff-golang-server-sdk/logger"
+	"github.com/harness/ff-golang-server-sdk/pkg/repository"
+	"github.com/harness/ff-golang-server-sdk/rest"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func int64Ptr(i int) *int64 {
+	ptr := int64(i)
+	return &ptr
+}
+
+var (
+	featureOne = rest.FeatureConfig{
+		Environment:  "123",
+		Feature:      "one",
+		Kind:         "boolean",
+		OffVariation: "false",
+		State:        "on",
+		Version:      int64Ptr(2),
+	}
+
+	featureTwo = rest.FeatureConfig{
+		Environment:  "123",
+		Feature:      "two",
+		Kind:         "boolean",
+		OffVariation: "false",
+		State:        "on",
+		Version:      int64Ptr(2),
+	}
+
+	segmentOne = rest.Segment{
+		CreatedAt:  int64Ptr(0),
+		Identifier: "one",
+		Name:       "one",
+		Version:    int64Ptr(2),
+	}
+)
+
+func TestRedisCache(t *testing.T) {
+	server := miniredis.RunT(t)
+	newCache := func(options ...RedisCacheOption) *RedisCache {
+		c := NewRedisCache(redis.NewClient(&redis.Options{Addr: server.Addr()}), options...)
+		c.SetLogger(logger.NewNoOpLogger())
+		return c
+	}
+	var _ cache.Cache = newCache()
+
+	writer := repository.New(newCache(WithRedisPrefix("env-123:")))
+	readerCache := newCache(WithRedisPrefix("env-123:"))
+	reader := repository.New(readerCache)
+
+	writer.SetFlags(true, "123", featureOne, featureTwo)
+	writer.SetFlag(featureOne, true)
+	writer.SetFlag(featureTwo, true)
+	writer.SetSegment(segmentOne, true)
+	assert.True(t, server.Exists("env-123:flag/one"))
+
+	t.Run("Flags written by another instance are read once refreshed", func(t *testing.T) {
+		_, err := reader.GetFlag("one")
+		assert.NotNil(t, err)
+
+		assert.Nil(t, readerCache.Refresh(context.Background()))
+		assert.False(t, readerCache.Updated().IsZero())
+
+		// values are decoded into the types the repository stored
+		flag, err := reader.GetFlag("one")
+		assert.Nil(t, err)
+		assert.Equal(t, featureOne, flag)
+
+		segment, err := reader.GetSegment("one")
+		assert.Nil(t, err)
+		assert.Equal(t, segmentOne, segment)
+
+		flags, err := reader.GetFlags()
+		assert.Nil(t, err)
+		assert.Equal(t, []rest.FeatureConfig{featureOne, featureTwo}, flags)
+	})
+
+	t.Run("Reads are served from the in-process copy", func(t *testing.T) {
+		server.SetError("unavailable")
+		defer server.SetError("")
+
+		flag, err := reader.GetFlag("one")
+		assert.Nil(t, err)
+		assert.Equal(t, featureOne, flag)
+
+		// a failed refresh keeps the previous copy
+		assert.NotNil(t, readerCache.Refresh(context.Background()))
+		_, err = reader.GetFlag("one")
+		assert.Nil(t, err)
+	})
+
+	t.Run("Deletes are seen by every instance once refreshed", func(t *testing.T) {
+		writer.DeleteFlag("two")
+		writer.DeleteFlags("123", "two")
+		assert.False(t, server.Exists("env-123:flag/two"))
+
+		assert.Nil(t, readerCache.Refresh(context.Background()))
+		_, err := reader.GetFlag("two")
+		assert.NotNil(t, err)
+		flags, err := reader.GetFlags()
+		assert.Nil(t, err)
+		assert.Equal(t, []rest.FeatureConfig{featureOne}, flags)
+	})
+
+	t.Run("Keys with another prefix are ignored", func(t *testing.T) {
+		otherCache := newCache(WithRedisPrefix("env-456:"))
+		assert.Nil(t, otherCache.Refresh(context.Background()))
+		flags, err := repository.New(otherCache).GetFlags()
+		assert.Nil(t, err)
+		assert.Empty(t, flags)
+	})
+
+	t.Run("A writer keeps serving what it stored if redis can't be written", func(t *testing.T) {
+		server.SetError("unavailable")
+		defer server.SetError("")
+
+		updated := featureOne
+		updated.Version = int64Ptr(3)
+		updated.State = "off"
+		writer.SetFlag(updated, false)
+
+		flag, err := writer.GetFlag("one")
+		assert.Nil(t, err)
+		assert.Equal(t, updated, flag)
+	})
+
+	t.Run("Purge removes every key with the prefix", func(t *testing.T) {
+		other := newCache(WithRedisPrefix("env-456:"))
+		other.Set("flag/one", featureOne)
+
+		c := newCache(WithRedisPrefix("env-123:"))
+		c.Purge()
+		assert.Equal(t, []string{"env-456:flag/one"}, server.Keys())
+		assert.Zero(t, c.Len())
+	})
+}
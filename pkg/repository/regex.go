@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"regexp"
+	"sync"
+
+	"github.com/harness/ff-golang-server-sdk/log"
+	"github.com/harness/ff-golang-server-sdk/rest"
+)
+
+const matchOperator = "match"
+
+// regexCache holds the compiled patterns of 'match' clauses so they're compiled once when a flag
+// or segment is stored rather than on every evaluation. Patterns are tracked per repository key so
+// they can be released when the flag or segment that uses them is updated or deleted.
+type regexCache struct {
+	mu       sync.RWMutex
+	patterns map[string]*regexp.Regexp
+	refs     map[string]int
+	owners   map[string][]string
+}
+
+func newRegexCache() *regexCache {
+	return &regexCache{
+		patterns: map[string]*regexp.Regexp{},
+		refs:     map[string]int{},
+		owners:   map[string][]string{},
+	}
+}
+
+// get returns the compiled pattern and whether it is known. A known pattern that failed to
+// compile is returned as nil.
+func (c *regexCache) get(pattern string) (*regexp.Regexp, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	re, ok := c.patterns[pattern]
+	return re, ok
+}
+
+// store replaces the patterns held for key, invalid patterns are logged as warnings
+func (c *regexCache) store(key string, patterns []string) {
+	if c == nil {
+		return
+	}
+
+	compiled := make(map[string]*regexp.Regexp, len(patterns))
+	for _, pattern := range patterns {
+		if _, ok := compiled[pattern]; ok {
+			continue
+		}
+		if re, ok := c.get(pattern); ok {
+			compiled[pattern] = re
+			continue
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			log.Warnf("invalid regular expression %q in %s, clauses using it will not match: %v", pattern, key, err)
+		}
+		compiled[pattern] = re
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.release(key)
+	if len(compiled) == 0 {
+		return
+	}
+	owned := make([]string, 0, len(compiled))
+	for pattern, re := range compiled {
+		c.patterns[pattern] = re
+		c.refs[pattern]++
+		owned = append(owned, pattern)
+	}
+	c.owners[key] = owned
+}
+
+// remove releases the patterns held for key
+func (c *regexCache) remove(key string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.release(key)
+}
+
+// release must be called with the lock held
+func (c *regexCache) release(key string) {
+	for _, pattern := range c.owners[key] {
+		c.refs[pattern]--
+		if c.refs[pattern] <= 0 {
+			delete(c.refs, pattern)
+			delete(c.patterns, pattern)
+		}
+	}
+	delete(c.owners, key)
+}
+
+func clausePatterns(patterns []string, clauses []rest.Clause) []string {
+	for _, clause := range clauses {
+		if clause.Op == matchOperator && len(clause.Values) > 0 {
+			patterns = append(patterns, clause.Values[0])
+		}
+	}
+	return patterns
+}
+
+func flagPatterns(featureConfigs ...rest.FeatureConfig) []string {
+	var patterns []string
+	for _, fc := range featureConfigs {
+		if fc.Rules == nil {
+			continue
+		}
+		for _, rule := range *fc.Rules {
+			patterns = clausePatterns(patterns, rule.Clauses)
+		}
+	}
+	return patterns
+}
+
+func segmentPatterns(segments ...rest.Segment) []string {
+	var patterns []string
+	for _, segment := range segments {
+		if segment.Rules != nil {
+			patterns = clausePatterns(patterns, *segment.Rules)
+		}
+		if segment.ServingRules != nil {
+			for _, rule := range *segment.ServingRules {
+				patterns = clausePatterns(patterns, rule.Clauses)
+			}
+		}
+	}
+	return patterns
+}
@@ -2,7 +2,11 @@ package repository
 
 import (
 	"fmt"
+	"reflect"
+	"regexp"
 	"sort"
+	"strings"
+	"sync"
 
 	"golang.org/x/exp/slices"
 
@@ -43,17 +47,32 @@ type Callback interface {
 	OnSegmentsDeleted(envID string, identifier string)
 }
 
+// Replacer is implemented by repositories that can replace every flag and segment in one step
+type Replacer interface {
+	ReplaceAll(envID string, flags []rest.FeatureConfig, segments []rest.Segment)
+}
+
 // FFRepository holds cache and optionally offline data
 type FFRepository struct {
 	cache    Cache
 	storage  storage.Storage
 	callback Callback
+	regexes  *regexCache
+	flags    *flagIndex
+	// mu is held for reading by the getters and for writing while flags and segments are stored or removed,
+	// so a reader never sees part of a ReplaceAll. Callbacks are called once it's released.
+	mu *sync.RWMutex
 }
 
+var _ Replacer = FFRepository{}
+
 // New repository with only cache capabillity
 func New(cache Cache) Repository {
 	return FFRepository{
-		cache: cache,
+		cache:   cache,
+		regexes: newRegexCache(),
+		flags:   newFlagIndex(),
+		mu:      &sync.RWMutex{},
 	}
 }
 
@@ -62,6 +81,9 @@ func NewWithStorage(cache Cache, storage storage.Storage) Repository {
 	return FFRepository{
 		cache:   cache,
 		storage: storage,
+		regexes: newRegexCache(),
+		flags:   newFlagIndex(),
+		mu:      &sync.RWMutex{},
 	}
 }
 
@@ -72,7 +94,28 @@ func NewWithStorageAndCallback(cache Cache, storage storage.Storage, callback Ca
 		cache:    cache,
 		storage:  storage,
 		callback: callback,
+		regexes:  newRegexCache(),
+		flags:    newFlagIndex(),
+		mu:       &sync.RWMutex{},
+	}
+}
+
+// lock holds the repository lock for writing and returns the function that releases it
+func (r FFRepository) lock() func() {
+	if r.mu == nil {
+		return func() {}
+	}
+	r.mu.Lock()
+	return r.mu.Unlock
+}
+
+// rlock holds the repository lock for reading and returns the function that releases it
+func (r FFRepository) rlock() func() {
+	if r.mu == nil {
+		return func() {}
 	}
+	r.mu.RLock()
+	return r.mu.RUnlock
 }
 
 func (r FFRepository) getFlags(envID string) ([]rest.FeatureConfig, error) {
@@ -104,27 +147,136 @@ func (r FFRepository) getFlagAndCache(identifier string, cacheable bool) (rest.F
 
 	if r.storage != nil {
 		flag, ok := r.storage.Get(flagKey)
-		if ok && cacheable {
-			r.cache.Set(flagKey, flag)
+		if ok {
+			if cacheable {
+				r.setCache(flagKey, flag)
+			}
+			return flag.(rest.FeatureConfig), nil
 		}
-		return flag.(rest.FeatureConfig), nil
 	}
 	return rest.FeatureConfig{}, fmt.Errorf("%w with identifier: %s", ErrFeatureConfigNotFound, identifier)
 }
 
 // GetFlag returns flag from cache or offline storage
 func (r FFRepository) GetFlag(identifier string) (rest.FeatureConfig, error) {
+	defer r.rlock()()
 	return r.getFlagAndCache(identifier, true)
 }
 
-// GetFlags returns all the flags /* Not implemented */
+// GetRegex returns the compiled regular expression for a 'match' clause pattern of a stored flag or
+// segment. The second value is false if the pattern isn't known, and the regular expression is nil if
+// the pattern is invalid.
+func (r FFRepository) GetRegex(pattern string) (*regexp.Regexp, bool) {
+	return r.regexes.get(pattern)
+}
+
+// GetFlags returns all the flags held in the repository, sorted by identifier. A flag stored by SetFlag is
+// read from its own key, and a flag only stored by SetFlags is read from the list of its environment.
 func (r FFRepository) GetFlags() ([]rest.FeatureConfig, error) {
-	return []rest.FeatureConfig{}, nil
+	defer r.rlock()()
+	return r.listFlags(), nil
+}
+
+// listFlags returns all the flags held in the repository, sorted by identifier
+func (r FFRepository) listFlags() []rest.FeatureConfig {
+	var indexed []indexedFlag
+	if shared, ok := r.cache.(SharedCache); ok && shared.Shared() {
+		indexed = r.sharedFlags(shared)
+	} else {
+		indexed = r.flags.list()
+	}
+
+	environments := map[string]map[string]rest.FeatureConfig{}
+	flags := make([]rest.FeatureConfig, 0, len(indexed))
+	for _, entry := range indexed {
+		flag, err := r.resolveFlag(entry, environments)
+		if err != nil {
+			// the flag has been evicted from the cache, so it isn't held any longer
+			log.Debugf("flag %s is indexed but not found in the repository: %s", entry.identifier, err)
+			continue
+		}
+		flags = append(flags, flag)
+	}
+	return flags
+}
+
+// resolveFlag reads an indexed flag from its own key, or from the lists of flags of its environments.
+// environments holds the lists that have already been read, keyed by environment and identifier.
+func (r FFRepository) resolveFlag(entry indexedFlag, environments map[string]map[string]rest.FeatureConfig) (rest.FeatureConfig, error) {
+	if entry.individual {
+		flag, err := r.getFlagAndCache(entry.identifier, true)
+		if err == nil || len(entry.envIDs) == 0 {
+			return flag, err
+		}
+	}
+	for _, envID := range entry.envIDs {
+		flags, ok := environments[envID]
+		if !ok {
+			list, err := r.getFlagsAndCache(envID, true)
+			if err != nil {
+				continue
+			}
+			flags = make(map[string]rest.FeatureConfig, len(list))
+			for _, flag := range list {
+				flags[flag.Feature] = flag
+			}
+			environments[envID] = flags
+		}
+		if flag, ok := flags[entry.identifier]; ok {
+			return flag, nil
+		}
+	}
+	return rest.FeatureConfig{}, fmt.Errorf("%w with identifier: %s", ErrFeatureConfigNotFound, entry.identifier)
+}
+
+// sharedFlags lists the flags held in a shared cache, which may have been stored by another instance,
+// sorted by identifier
+func (r FFRepository) sharedFlags(cache SharedCache) []indexedFlag {
+	flags := map[string]*indexedFlag{}
+	get := func(identifier string) *indexedFlag {
+		flag, ok := flags[identifier]
+		if !ok {
+			flag = &indexedFlag{identifier: identifier}
+			flags[identifier] = flag
+		}
+		return flag
+	}
+
+	flagPrefix, flagsPrefix := formatFlagKey(""), formatFlagsKey("")
+	for _, key := range cache.Keys() {
+		k, ok := key.(string)
+		if !ok {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(k, flagPrefix):
+			get(strings.TrimPrefix(k, flagPrefix)).individual = true
+		case strings.HasPrefix(k, flagsPrefix):
+			envID := strings.TrimPrefix(k, flagsPrefix)
+			list, err := r.getFlagsAndCache(envID, false)
+			if err != nil {
+				continue
+			}
+			for _, flag := range list {
+				entry := get(flag.Feature)
+				entry.envIDs = append(entry.envIDs, envID)
+			}
+		}
+	}
+	return sortIndexedFlags(flags)
 }
 
-// GetFlagMap returns all flags as a mao /* Not Implemented *.
+// GetFlagMap returns all the flags held in the repository keyed by identifier
 func (r FFRepository) GetFlagMap() (map[string]*rest.FeatureConfig, error) {
-	return map[string]*rest.FeatureConfig{}, nil
+	flags, err := r.GetFlags()
+	if err != nil {
+		return nil, err
+	}
+	flagMap := make(map[string]*rest.FeatureConfig, len(flags))
+	for i := range flags {
+		flagMap[flags[i].Feature] = &flags[i]
+	}
+	return flagMap, nil
 }
 
 func (r FFRepository) getSegmentAndCache(identifier string, cacheable bool) (rest.Segment, error) {
@@ -136,25 +288,39 @@ func (r FFRepository) getSegmentAndCache(identifier string, cacheable bool) (res
 
 	if r.storage != nil {
 		flag, ok := r.storage.Get(segmentKey)
-		if ok && cacheable {
-			r.cache.Set(segmentKey, flag)
+		if ok {
+			if cacheable {
+				r.setCache(segmentKey, flag)
+			}
+			return flag.(rest.Segment), nil
 		}
-		return flag.(rest.Segment), nil
 	}
 	return rest.Segment{}, fmt.Errorf("%w with identifier: %s", ErrSegmentNotFound, identifier)
 }
 
 // GetSegment returns flag from cache or offline storage
 func (r FFRepository) GetSegment(identifier string) (rest.Segment, error) {
+	defer r.rlock()()
 	return r.getSegmentAndCache(identifier, true)
 }
 
 // SetFlag places a flag in the repository with the new value
 func (r FFRepository) SetFlag(featureConfig rest.FeatureConfig, initialLoad bool) {
+	unlock := r.lock()
+	stored := r.storeFlag(featureConfig, initialLoad)
+	unlock()
+
+	if stored && r.callback != nil {
+		r.callback.OnFlagStored(featureConfig.Feature)
+	}
+}
+
+// storeFlag places a flag in the repository, it returns false if the flag was up to date
+func (r FFRepository) storeFlag(featureConfig rest.FeatureConfig, initialLoad bool) bool {
 	if !initialLoad {
 		// If the flag is up to date then we don't need to bother updating the cache
 		if !r.isFlagOutdated(featureConfig) {
-			return
+			return false
 		}
 	}
 
@@ -167,20 +333,30 @@ func (r FFRepository) SetFlag(featureConfig rest.FeatureConfig, initialLoad bool
 		}
 		r.cache.Remove(flagKey)
 	} else {
-		r.cache.Set(flagKey, featureConfig)
-	}
-
-	if r.callback != nil {
-		r.callback.OnFlagStored(featureConfig.Feature)
+		r.setCache(flagKey, featureConfig)
 	}
+	r.regexes.store(flagKey, flagPatterns(featureConfig))
+	r.flags.add(featureConfig.Feature)
+	return true
 }
 
 // SetFlags places all the flags in the repository
 func (r FFRepository) SetFlags(initialLoad bool, envID string, featureConfigs ...rest.FeatureConfig) {
+	unlock := r.lock()
+	stored := r.storeFlags(initialLoad, envID, featureConfigs...)
+	unlock()
+
+	if stored && r.callback != nil {
+		r.callback.OnFlagsStored(envID)
+	}
+}
+
+// storeFlags places all the flags in the list of an environment, it returns false if they were up to date
+func (r FFRepository) storeFlags(initialLoad bool, envID string, featureConfigs ...rest.FeatureConfig) bool {
 	if !initialLoad {
 		// If the flags are all up to date then we don't need to bother updating the cache and can exit
 		if !r.areFlagsOutdated(envID, featureConfigs...) {
-			return
+			return false
 		}
 	}
 
@@ -196,20 +372,34 @@ func (r FFRepository) SetFlags(initialLoad bool, envID string, featureConfigs ..
 		}
 		r.cache.Remove(key)
 	} else {
-		r.cache.Set(key, featureConfigs)
+		r.setCache(key, featureConfigs)
 	}
-
-	if r.callback != nil {
-		r.callback.OnFlagsStored(envID)
+	r.regexes.store(key, flagPatterns(featureConfigs...))
+	identifiers := make([]string, 0, len(featureConfigs))
+	for _, fc := range featureConfigs {
+		identifiers = append(identifiers, fc.Feature)
 	}
+	r.flags.setEnvironment(envID, identifiers...)
+	return true
 }
 
 // SetSegment places a segment in the repository with the new value
 func (r FFRepository) SetSegment(segment rest.Segment, initialLoad bool) {
+	unlock := r.lock()
+	stored := r.storeSegment(segment, initialLoad)
+	unlock()
+
+	if stored && r.callback != nil {
+		r.callback.OnSegmentStored(segment.Identifier)
+	}
+}
+
+// storeSegment places a segment in the repository, it returns false if the segment was up to date
+func (r FFRepository) storeSegment(segment rest.Segment, initialLoad bool) bool {
 	if !initialLoad {
 		// If the segment isn't outdated then we can exit as we don't need to refresh the cache
 		if !r.isSegmentOutdated(segment) {
-			return
+			return false
 		}
 	}
 	SortSegmentServingGroups(&segment)
@@ -220,20 +410,29 @@ func (r FFRepository) SetSegment(segment rest.Segment, initialLoad bool) {
 		}
 		r.cache.Remove(segmentKey)
 	} else {
-		r.cache.Set(segmentKey, segment)
-	}
-
-	if r.callback != nil {
-		r.callback.OnSegmentStored(segment.Identifier)
+		r.setCache(segmentKey, segment)
 	}
+	r.regexes.store(segmentKey, segmentPatterns(segment))
+	return true
 }
 
 // SetSegments places all the segments in the repository
 func (r FFRepository) SetSegments(initialLoad bool, envID string, segments ...rest.Segment) {
+	unlock := r.lock()
+	stored := r.storeSegments(initialLoad, envID, segments...)
+	unlock()
+
+	if stored && r.callback != nil {
+		r.callback.OnSegmentsStored(envID)
+	}
+}
+
+// storeSegments places all the segments in the list of an environment, it returns false if they were up to date
+func (r FFRepository) storeSegments(initialLoad bool, envID string, segments ...rest.Segment) bool {
 	if !initialLoad {
 		// If segments aren't outdated then we can exit as we don't need to refresh the cache
 		if !r.areSegmentsOutdated(envID, segments...) {
-			return
+			return false
 		}
 	}
 
@@ -250,16 +449,25 @@ func (r FFRepository) SetSegments(initialLoad bool, envID string, segments ...re
 		}
 		r.cache.Remove(key)
 	} else {
-		r.cache.Set(key, segments)
+		r.setCache(key, segments)
 	}
+	r.regexes.store(key, segmentPatterns(segments...))
+	return true
+}
+
+// DeleteFlag removes a flag from the repository
+func (r FFRepository) DeleteFlag(identifier string) {
+	unlock := r.lock()
+	r.removeFlag(identifier)
+	unlock()
 
 	if r.callback != nil {
-		r.callback.OnSegmentsStored(envID)
+		r.callback.OnFlagDeleted(identifier)
 	}
 }
 
-// DeleteFlag removes a flag from the repository
-func (r FFRepository) DeleteFlag(identifier string) {
+// removeFlag removes a flag stored under its own key
+func (r FFRepository) removeFlag(identifier string) {
 	flagKey := formatFlagKey(identifier)
 	if r.storage != nil {
 		// remove from storage
@@ -269,9 +477,8 @@ func (r FFRepository) DeleteFlag(identifier string) {
 	}
 	// remove from cache
 	r.cache.Remove(flagKey)
-	if r.callback != nil {
-		r.callback.OnFlagDeleted(identifier)
-	}
+	r.regexes.remove(flagKey)
+	r.flags.remove(identifier)
 }
 
 // DeleteFlags removes a flag from the flags key.
@@ -280,7 +487,19 @@ func (r FFRepository) DeleteFlag(identifier string) {
 // haven't been deleted. So we have to first fetch value, then remove the specific flag that has been deleted
 // and update the key in the cache/storage
 func (r FFRepository) DeleteFlags(envID string, identifier string) {
+	unlock := r.lock()
+	removed := r.removeFromFlags(envID, identifier)
+	unlock()
+
+	if removed && r.callback != nil {
+		r.callback.OnFlagsDeleted(envID, identifier)
+	}
+}
+
+// removeFromFlags removes a flag from the list of an environment, it returns false if the list isn't held
+func (r FFRepository) removeFromFlags(envID string, identifier string) bool {
 	flagsKey := formatFlagsKey(envID)
+	r.flags.removeFromEnvironment(envID, identifier)
 	if r.storage != nil {
 		// remove from storage
 		if err := r.storage.Remove(flagsKey); err != nil {
@@ -291,27 +510,36 @@ func (r FFRepository) DeleteFlags(envID string, identifier string) {
 	value, ok := r.cache.Get(flagsKey)
 	if !ok {
 		log.Errorf("error fetching flags from cache for env=%s", envID)
-		return
+		return false
 	}
 
 	featureConfigs, ok := value.([]rest.FeatureConfig)
 	if !ok {
 		log.Errorf("failed to delete flags, expected type to be []rest.FeatureConfig but got %T", featureConfigs)
-		return
+		return false
 	}
 
 	updatedFeatureConfigs := slices.DeleteFunc(featureConfigs, func(element rest.FeatureConfig) bool {
 		return element.Feature == identifier
 	})
-	r.cache.Set(flagsKey, updatedFeatureConfigs)
+	r.setCache(flagsKey, updatedFeatureConfigs)
+	r.regexes.store(flagsKey, flagPatterns(updatedFeatureConfigs...))
+	return true
+}
+
+// DeleteSegment removes a segment from the repository
+func (r FFRepository) DeleteSegment(identifier string) {
+	unlock := r.lock()
+	r.removeSegment(identifier)
+	unlock()
 
 	if r.callback != nil {
-		r.callback.OnFlagsDeleted(envID, identifier)
+		r.callback.OnSegmentDeleted(identifier)
 	}
 }
 
-// DeleteSegment removes a segment from the repository
-func (r FFRepository) DeleteSegment(identifier string) {
+// removeSegment removes a segment stored under its own key
+func (r FFRepository) removeSegment(identifier string) {
 	segmentKey := formatSegmentKey(identifier)
 	if r.storage != nil {
 		// remove from storage
@@ -321,9 +549,7 @@ func (r FFRepository) DeleteSegment(identifier string) {
 	}
 	// remove from cache
 	r.cache.Remove(segmentKey)
-	if r.callback != nil {
-		r.callback.OnSegmentDeleted(identifier)
-	}
+	r.regexes.remove(segmentKey)
 }
 
 // DeleteSegments removes a Segment from the segments key.
@@ -332,6 +558,17 @@ func (r FFRepository) DeleteSegment(identifier string) {
 // haven't been deleted. So we have to first fetch value, then remove the specific segment that has been deleted
 // and update the key in the cache/storage
 func (r FFRepository) DeleteSegments(envID string, identifier string) {
+	unlock := r.lock()
+	removed := r.removeFromSegments(envID, identifier)
+	unlock()
+
+	if removed && r.callback != nil {
+		r.callback.OnSegmentsDeleted(envID, identifier)
+	}
+}
+
+// removeFromSegments removes a segment from the list of an environment, it returns false if the list isn't held
+func (r FFRepository) removeFromSegments(envID string, identifier string) bool {
 	segmentsKey := formatSegmentsKey(envID)
 	if r.storage != nil {
 		// remove from storage
@@ -343,22 +580,92 @@ func (r FFRepository) DeleteSegments(envID string, identifier string) {
 	value, ok := r.cache.Get(segmentsKey)
 	if !ok {
 		log.Errorf("error fetching segments from cache for env=%s", envID)
-		return
+		return false
 	}
 
 	segments, ok := value.([]rest.Segment)
 	if !ok {
 		log.Errorf("failed to delete flags, expected type to be []rest.Segment but got %T", segments)
-		return
+		return false
 	}
 
 	updatedSegments := slices.DeleteFunc(segments, func(element rest.Segment) bool {
 		return element.Identifier == identifier
 	})
-	r.cache.Set(segmentsKey, updatedSegments)
+	r.setCache(segmentsKey, updatedSegments)
+	r.regexes.store(segmentsKey, segmentPatterns(updatedSegments...))
+	return true
+}
 
-	if r.callback != nil {
-		r.callback.OnSegmentsDeleted(envID, identifier)
+// ReplaceAll replaces every flag and segment held in the repository with flags and segments, which become the
+// lists of envID. The repository lock is held while they're replaced, so readers see either the previous
+// flags and segments or the new ones and never a mix of both. Callbacks are then called for the flags and
+// segments that were added, changed or removed.
+func (r FFRepository) ReplaceAll(envID string, flags []rest.FeatureConfig, segments []rest.Segment) {
+	for i := range flags {
+		SortFeatureConfigServingRules(&flags[i])
+	}
+	for i := range segments {
+		SortSegmentServingGroups(&segments[i])
+	}
+
+	unlock := r.lock()
+	previousFlags := map[string]rest.FeatureConfig{}
+	for _, flag := range r.listFlags() {
+		previousFlags[flag.Feature] = flag
+	}
+	previousSegments := map[string]rest.Segment{}
+	if list, err := r.getSegments(envID); err == nil {
+		for _, segment := range list {
+			if stored, err := r.getSegmentAndCache(segment.Identifier, false); err == nil {
+				previousSegments[segment.Identifier] = stored
+			}
+		}
+	}
+
+	r.storeSegments(true, envID, segments...)
+	r.storeFlags(true, envID, flags...)
+	var storedSegments, storedFlags, deletedSegments, deletedFlags []string
+	for _, segment := range segments {
+		r.storeSegment(segment, true)
+		if previous, ok := previousSegments[segment.Identifier]; !ok || !reflect.DeepEqual(previous, segment) {
+			storedSegments = append(storedSegments, segment.Identifier)
+		}
+		delete(previousSegments, segment.Identifier)
+	}
+	for _, flag := range flags {
+		r.storeFlag(flag, true)
+		if previous, ok := previousFlags[flag.Feature]; !ok || !reflect.DeepEqual(previous, flag) {
+			storedFlags = append(storedFlags, flag.Feature)
+		}
+		delete(previousFlags, flag.Feature)
+	}
+	for identifier := range previousFlags {
+		r.removeFlag(identifier)
+		deletedFlags = append(deletedFlags, identifier)
+	}
+	for identifier := range previousSegments {
+		r.removeSegment(identifier)
+		deletedSegments = append(deletedSegments, identifier)
+	}
+	unlock()
+
+	if r.callback == nil {
+		return
+	}
+	sort.Strings(deletedFlags)
+	sort.Strings(deletedSegments)
+	for _, identifier := range storedSegments {
+		r.callback.OnSegmentStored(identifier)
+	}
+	for _, identifier := range storedFlags {
+		r.callback.OnFlagStored(identifier)
+	}
+	for _, identifier := range deletedFlags {
+		r.callback.OnFlagDeleted(identifier)
+	}
+	for _, identifier := range deletedSegments {
+		r.callback.OnSegmentDeleted(identifier)
 	}
 }
 
@@ -382,7 +689,7 @@ func (r FFRepository) getFlagsAndCache(envID string, cacheable bool) ([]rest.Fea
 	if r.storage != nil {
 		flag, ok := r.storage.Get(flagKey)
 		if ok && cacheable {
-			r.cache.Set(flagKey, flag)
+			r.setCache(flagKey, flag)
 			return flag.([]rest.FeatureConfig), nil
 		}
 	}
@@ -452,13 +759,6 @@ func (r FFRepository) areSegmentsOutdated(envID string, segments ...rest.Segment
 		}
 	}
 	return false
-
-	for _, segment := range segments {
-		if r.isSegmentOutdated(segment) {
-			return true
-		}
-	}
-	return false
 }
 
 // SortFeatureConfigServingRules sorts the serving rules of a FeatureConfig by priority
@@ -487,6 +787,14 @@ func SortSegmentServingGroups(segment *rest.Segment) {
 	})
 }
 
+// setCache stores the value in the cache. Flags and segments are only held in the cache, so an
+// eviction means something will be evaluated as missing until it's retrieved again.
+func (r FFRepository) setCache(key string, value interface{}) {
+	if evicted := r.cache.Set(key, value); evicted {
+		log.Errorf("the cache evicted an entry to store %s, increase the capacity of the cache", key)
+	}
+}
+
 // Close all resources
 func (r FFRepository) Close() {
 
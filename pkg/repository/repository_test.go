@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/harness/ff-golang-server-sdk/cache"
 	"github.com/harness/ff-golang-server-sdk/logger"
 	"github.com/harness/ff-golang-server-sdk/rest"
 	"github.com/stretchr/testify/assert"
@@ -645,3 +646,251 @@ func TestFFRepository_SetSegment(t *testing.T) {
 		})
 	}
 }
+
+func TestFFRepository_GetRegex(t *testing.T) {
+	matchRule := func(pattern string) *[]rest.ServingRule {
+		return &[]rest.ServingRule{{
+			Clauses: []rest.Clause{{Attribute: "email", Op: "match", Values: []string{pattern}}},
+		}}
+	}
+
+	lru, err := cache.NewLruCache(100, logger.NewNoOpLogger())
+	assert.Nil(t, err)
+	repo := New(lru).(FFRepository)
+
+	flag := featureOne
+	flag.Rules = matchRule(`@harness\.io$`)
+	repo.SetFlag(flag, true)
+
+	re, ok := repo.GetRegex(`@harness\.io$`)
+	assert.True(t, ok)
+	assert.True(t, re.MatchString("john@harness.io"))
+
+	// an invalid pattern is known but has no compiled regex
+	invalid := featureTwo
+	invalid.Rules = matchRule(`[a-`)
+	repo.SetFlag(invalid, true)
+	re, ok = repo.GetRegex(`[a-`)
+	assert.True(t, ok)
+	assert.Nil(t, re)
+
+	// updating the flag releases the old pattern
+	flag.Rules = matchRule(`@example\.com$`)
+	flag.Version = int64Ptr(3)
+	repo.SetFlag(flag, false)
+	_, ok = repo.GetRegex(`@harness\.io$`)
+	assert.False(t, ok)
+	_, ok = repo.GetRegex(`@example\.com$`)
+	assert.True(t, ok)
+
+	// a pattern shared by a segment survives the flag being deleted
+	segment := segmentOne
+	segment.Rules = &[]rest.Clause{{Attribute: "email", Op: "match", Values: []string{`@example\.com$`}}}
+	repo.SetSegment(segment, true)
+	repo.DeleteFlag(flag.Feature)
+	_, ok = repo.GetRegex(`@example\.com$`)
+	assert.True(t, ok)
+
+	repo.DeleteSegment(segment.Identifier)
+	_, ok = repo.GetRegex(`@example\.com$`)
+	assert.False(t, ok)
+}
+
+func TestFFRepository_GetFlags(t *testing.T) {
+	lru, err := cache.NewLruCache(100, logger.NewNoOpLogger())
+	assert.Nil(t, err)
+	repo := New(lru)
+
+	flags, err := repo.GetFlags()
+	assert.Nil(t, err)
+	assert.Empty(t, flags)
+
+	repo.SetFlags(true, "123", featureTwo, featureOne)
+	for _, f := range []rest.FeatureConfig{featureTwo, featureOne} {
+		repo.SetFlag(f, true)
+	}
+
+	flags, err = repo.GetFlags()
+	assert.Nil(t, err)
+	assert.Equal(t, []rest.FeatureConfig{featureOne, featureTwo}, flags)
+
+	flagMap, err := repo.GetFlagMap()
+	assert.Nil(t, err)
+	assert.Len(t, flagMap, 2)
+	assert.Equal(t, featureTwo, *flagMap["two"])
+
+	repo.DeleteFlag("one")
+	repo.DeleteFlags("123", "one")
+	flags, err = repo.GetFlags()
+	assert.Nil(t, err)
+	assert.Equal(t, []rest.FeatureConfig{featureTwo}, flags)
+}
+
+func TestFFRepository_GetFlagsStoredWithSetFlags(t *testing.T) {
+	lru, err := cache.NewLruCache(100, logger.NewNoOpLogger())
+	assert.Nil(t, err)
+	repo := New(lru)
+
+	t.Log("When flags are only stored in the list of their environment they're listed")
+	repo.SetFlags(true, "123", featureTwo, featureOne)
+	flags, err := repo.GetFlags()
+	assert.Nil(t, err)
+	assert.Equal(t, []rest.FeatureConfig{featureOne, featureTwo}, flags)
+
+	t.Log("When a flag is deleted from the list it's no longer listed")
+	repo.DeleteFlags("123", "one")
+	flags, err = repo.GetFlags()
+	assert.Nil(t, err)
+	assert.Equal(t, []rest.FeatureConfig{featureTwo}, flags)
+
+	t.Log("When the list is replaced flags that aren't in the new list are no longer listed")
+	repo.SetFlags(true, "123", featureOne)
+	flags, err = repo.GetFlags()
+	assert.Nil(t, err)
+	assert.Equal(t, []rest.FeatureConfig{featureOne}, flags)
+
+	t.Log("When a flag is stored both ways it's listed until it's deleted from both")
+	repo.SetFlag(featureOne, true)
+	repo.DeleteFlags("123", "one")
+	flags, err = repo.GetFlags()
+	assert.Nil(t, err)
+	assert.Equal(t, []rest.FeatureConfig{featureOne}, flags)
+
+	repo.DeleteFlag("one")
+	flags, err = repo.GetFlags()
+	assert.Nil(t, err)
+	assert.Empty(t, flags)
+}
+
+func TestFFRepository_CacheEvictsLeastRecentlyUsed(t *testing.T) {
+	lru, err := cache.NewLruCache(2, logger.NewNoOpLogger())
+	assert.Nil(t, err)
+	repo := New(lru)
+
+	featureThree := featureTwo
+	featureThree.Feature = "three"
+	for _, f := range []rest.FeatureConfig{featureOne, featureTwo, featureThree} {
+		repo.SetFlag(f, true)
+	}
+
+	assert.Equal(t, 2, lru.Len())
+	assert.Equal(t, 2, lru.Cap())
+	_, err = repo.GetFlag(featureOne.Feature)
+	assert.NotNil(t, err)
+	for _, f := range []rest.FeatureConfig{featureTwo, featureThree} {
+		_, err := repo.GetFlag(f.Feature)
+		assert.Nil(t, err)
+	}
+	assert.False(t, lru.Updated().IsZero())
+}
+
+func TestNewLruCache(t *testing.T) {
+	lru, err := NewLruCache(2)
+	assert.Nil(t, err)
+	repo := New(lru)
+	repo.SetFlag(featureOne, true)
+	_, err = repo.GetFlag(featureOne.Feature)
+	assert.Nil(t, err)
+
+	withLogger, err := NewLruCacheWithLogger(2, logger.NewNoOpLogger())
+	assert.Nil(t, err)
+	repo = New(withLogger)
+	repo.SetFlag(featureOne, true)
+	assert.True(t, withLogger.Contains("flag/"+featureOne.Feature))
+	assert.False(t, withLogger.Updated().IsZero())
+}
+
+// recordingCallback records the flags and segments it's told were stored or deleted
+type recordingCallback struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (c *recordingCallback) record(event string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.events = append(c.events, event)
+}
+
+func (c *recordingCallback) OnFlagStored(identifier string)  { c.record("flag stored " + identifier) }
+func (c *recordingCallback) OnFlagsStored(envID string)      { c.record("flags stored " + envID) }
+func (c *recordingCallback) OnFlagDeleted(identifier string) { c.record("flag deleted " + identifier) }
+func (c *recordingCallback) OnFlagsDeleted(envID string, identifier string) {
+	c.record("flags deleted " + envID + " " + identifier)
+}
+func (c *recordingCallback) OnSegmentStored(identifier string) {
+	c.record("segment stored " + identifier)
+}
+func (c *recordingCallback) OnSegmentsStored(envID string) { c.record("segments stored " + envID) }
+func (c *recordingCallback) OnSegmentDeleted(identifier string) {
+	c.record("segment deleted " + identifier)
+}
+func (c *recordingCallback) OnSegmentsDeleted(envID string, identifier string) {
+	c.record("segments deleted " + envID + " " + identifier)
+}
+
+func TestFFRepository_ReplaceAll(t *testing.T) {
+	lru, err := cache.NewLruCache(100, logger.NewNoOpLogger())
+	assert.Nil(t, err)
+	callback := &recordingCallback{}
+	repo := NewWithStorageAndCallback(lru, nil, callback).(FFRepository)
+
+	repo.ReplaceAll("123", []rest.FeatureConfig{featureOne, featureTwo}, []rest.Segment{segmentOne, segmentTwo})
+	assert.Equal(t, []string{"segment stored one", "segment stored two", "flag stored one", "flag stored two"}, callback.events)
+
+	t.Log("When the snapshot is replaced only the changes are notified")
+	callback.events = nil
+	updated := featureOne
+	updated.State = "off"
+	repo.ReplaceAll("123", []rest.FeatureConfig{updated}, []rest.Segment{segmentOne})
+	assert.Equal(t, []string{"flag stored one", "flag deleted two", "segment deleted two"}, callback.events)
+
+	flags, err := repo.GetFlags()
+	assert.Nil(t, err)
+	assert.Equal(t, []rest.FeatureConfig{updated}, flags)
+	_, err = repo.GetSegment("two")
+	assert.NotNil(t, err)
+	segment, err := repo.GetSegment("one")
+	assert.Nil(t, err)
+	assert.Equal(t, segmentOne, segment)
+
+	t.Log("When the same snapshot is replaced again nothing is notified")
+	callback.events = nil
+	repo.ReplaceAll("123", []rest.FeatureConfig{updated}, []rest.Segment{segmentOne})
+	assert.Empty(t, callback.events)
+}
+
+func TestFFRepository_ReplaceAllIsAtomic(t *testing.T) {
+	lru, err := cache.NewLruCache(100, logger.NewNoOpLogger())
+	assert.Nil(t, err)
+	repo := New(lru).(FFRepository)
+
+	snapshot := func(version int) []rest.FeatureConfig {
+		one, two := featureOne, featureTwo
+		one.Version, two.Version = int64Ptr(version), int64Ptr(version)
+		return []rest.FeatureConfig{one, two}
+	}
+	repo.ReplaceAll("123", snapshot(0), nil)
+
+	const versions = 200
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i <= versions; i++ {
+			repo.ReplaceAll("123", snapshot(i), nil)
+		}
+	}()
+
+	for finished := false; !finished; {
+		select {
+		case <-done:
+			finished = true
+		default:
+		}
+		flags, err := repo.GetFlags()
+		assert.Nil(t, err)
+		if assert.Len(t, flags, 2) && *flags[0].Version != *flags[1].Version {
+			t.Fatalf("read a mix of snapshots: %d and %d", *flags[0].Version, *flags[1].Version)
+		}
+	}
+}
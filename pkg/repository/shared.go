@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/harness/ff-golang-server-sdk/rest"
+)
+
+// SharedCache is implemented by caches that are shared with other processes. The repository lists the
+// flags held by a shared cache rather than only the flags it stored itself, so that instances reading
+// from a cache written by another instance see every flag.
+type SharedCache interface {
+	Cache
+	Shared() bool
+}
+
+// RefreshableCache is a SharedCache that serves reads from an in-process copy of the shared data.
+// Refresh replaces the copy with the data currently held by the shared store.
+type RefreshableCache interface {
+	SharedCache
+	Refresh(ctx context.Context) error
+}
+
+// DecodeCacheValue decodes the JSON encoding of a value the repository stored under key into the rest
+// type the repository expects, for caches that hold their values outside of the process
+func DecodeCacheValue(key string, encoded []byte) (interface{}, error) {
+	var err error
+	switch {
+	case strings.HasPrefix(key, formatFlagKey("")):
+		var flag rest.FeatureConfig
+		err = jsoniter.Unmarshal(encoded, &flag)
+		return flag, err
+	case strings.HasPrefix(key, formatFlagsKey("")):
+		var flags []rest.FeatureConfig
+		err = jsoniter.Unmarshal(encoded, &flags)
+		return flags, err
+	case strings.HasPrefix(key, formatSegmentKey("")):
+		var segment rest.Segment
+		err = jsoniter.Unmarshal(encoded, &segment)
+		return segment, err
+	case strings.HasPrefix(key, formatSegmentsKey("")):
+		var segments []rest.Segment
+		err = jsoniter.Unmarshal(encoded, &segments)
+		return segments, err
+	}
+	var value interface{}
+	err = jsoniter.Unmarshal(encoded, &value)
+	return value, err
+}
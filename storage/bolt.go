@@ -0,0 +1,202 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/harness/ff-golang-server-sdk/logger"
+)
+
+// boltBucket holds every key written by the store
+var boltBucket = []byte("harness-ffm")
+
+// boltLockTimeout is how long to wait for another process to release the database
+const boltLockTimeout = 5 * time.Second
+
+// BoltStore is a Storage backed by a bbolt database. Unlike FileStore, Persist only writes the keys
+// that were set or removed since the last Persist, so large flag sets aren't rewritten on every change.
+//
+// The database is only opened while it is being read or written, so several processes on the same host
+// can share it: loads take a shared lock and persists an exclusive one, waiting for each other.
+type BoltStore struct {
+	path          string
+	mu            sync.Mutex
+	data          map[string]interface{}
+	dirty         map[string]struct{}
+	lastPersisted time.Time
+	logger        logger.Logger
+}
+
+// NewBoltStore creates a new bbolt store instance
+func NewBoltStore(project string, path string, logger logger.Logger) *BoltStore {
+	return &BoltStore{
+		path:   filepath.Join(path, fmt.Sprintf("harness-ffm-v1-%s.db", project)),
+		data:   make(map[string]interface{}),
+		dirty:  make(map[string]struct{}),
+		logger: logger,
+	}
+}
+
+// Reset data with custom value, if persist is true save it to the store. Only keys that were added,
+// removed or whose value changed are written when it's persisted.
+func (bs *BoltStore) Reset(data map[string]interface{}, persist bool) error {
+	bs.mu.Lock()
+	for key := range bs.data {
+		if _, ok := data[key]; !ok {
+			bs.dirty[key] = struct{}{}
+		}
+	}
+	for key, value := range data {
+		if old, ok := bs.data[key]; !ok || !reflect.DeepEqual(old, value) {
+			bs.dirty[key] = struct{}{}
+		}
+	}
+	bs.data = data
+	bs.mu.Unlock()
+
+	if persist {
+		return bs.Persist()
+	}
+	return nil
+}
+
+// Load data from the store
+func (bs *BoltStore) Load() error {
+	db, err := bolt.Open(bs.path, 0600, &bolt.Options{Timeout: boltLockTimeout, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer bs.close(db)
+
+	data := make(map[string]interface{})
+	err = db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			var value interface{}
+			if err := json.Unmarshal(v, &value); err != nil {
+				return fmt.Errorf("key %s: %w", k, err)
+			}
+			data[string(k)] = value
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	bs.data = data
+	bs.dirty = make(map[string]struct{})
+	return nil
+}
+
+// Persist writes the keys that changed since the last Persist in a single transaction
+func (bs *BoltStore) Persist() error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	db, err := bolt.Open(bs.path, 0600, &bolt.Options{Timeout: boltLockTimeout})
+	if err != nil {
+		return err
+	}
+	defer bs.close(db)
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(boltBucket)
+		if err != nil {
+			return err
+		}
+		for key := range bs.dirty {
+			value, ok := bs.data[key]
+			if !ok {
+				if err := bucket.Delete([]byte(key)); err != nil {
+					return err
+				}
+				continue
+			}
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return fmt.Errorf("key %s: %w", key, err)
+			}
+			if bytes.Equal(bucket.Get([]byte(key)), encoded) {
+				continue
+			}
+			if err := bucket.Put([]byte(key), encoded); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	bs.dirty = make(map[string]struct{})
+	bs.lastPersisted = time.Now()
+	return nil
+}
+
+func (bs *BoltStore) close(db *bolt.DB) {
+	if err := db.Close(); err != nil {
+		bs.logger.Errorf("error closing database, err: %v", err)
+	}
+}
+
+// Get value with the specified key
+func (bs *BoltStore) Get(key string) (interface{}, bool) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	val, ok := bs.data[key]
+	return val, ok
+}
+
+// List all values
+func (bs *BoltStore) List() []interface{} {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	values := make([]interface{}, 0, len(bs.data))
+	for _, val := range bs.data {
+		values = append(values, val)
+	}
+	return values
+}
+
+// Set new key and value
+func (bs *BoltStore) Set(key string, value interface{}) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	bs.data[key] = value
+	bs.dirty[key] = struct{}{}
+	return nil
+}
+
+// Remove object from data store identified by key parameter
+func (bs *BoltStore) Remove(key string) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	delete(bs.data, key)
+	bs.dirty[key] = struct{}{}
+	return nil
+}
+
+// PersistedAt returns when it was last recorded
+func (bs *BoltStore) PersistedAt() time.Time {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	return bs.lastPersisted
+}
+
+// SetLogger set logger
+func (bs *BoltStore) SetLogger(logger logger.Logger) {
+	bs.logger = logger
+}
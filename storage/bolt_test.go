@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/harness/ff-golang-server-sdk/logger"
+	"github.com/stretchr/testify/assert"
+	bolt "go.etcd.io/bbolt"
+)
+
+func newTestBoltStore(dir string) *BoltStore {
+	return NewBoltStore("test", dir, logger.NewNoOpLogger())
+}
+
+// boltKeys reads the keys and values held in the database
+func boltKeys(t *testing.T, dir string) map[string]string {
+	db, err := bolt.Open(filepath.Join(dir, "harness-ffm-v1-test.db"), 0600, &bolt.Options{ReadOnly: true})
+	assert.Nil(t, err)
+	defer db.Close()
+
+	keys := map[string]string{}
+	assert.Nil(t, db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).ForEach(func(k, v []byte) error {
+			keys[string(k)] = string(v)
+			return nil
+		})
+	}))
+	return keys
+}
+
+func TestBoltStore_PersistAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	assert.True(t, os.IsNotExist(newTestBoltStore(dir).Load()))
+
+	store := newTestBoltStore(dir)
+	assert.Nil(t, store.Set("flag/darkMode", "on"))
+	assert.Nil(t, store.Set("flag/beta", "off"))
+	assert.Nil(t, store.Persist())
+	assert.Equal(t, map[string]string{"flag/darkMode": `"on"`, "flag/beta": `"off"`}, boltKeys(t, dir))
+
+	assert.Nil(t, store.Remove("flag/beta"))
+	assert.Nil(t, store.Persist())
+
+	loaded := newTestBoltStore(dir)
+	assert.Nil(t, loaded.Load())
+	value, ok := loaded.Get("flag/darkMode")
+	assert.True(t, ok)
+	assert.Equal(t, "on", value)
+	_, ok = loaded.Get("flag/beta")
+	assert.False(t, ok)
+}
+
+func TestBoltStore_PersistOnlyWritesChangedKeys(t *testing.T) {
+	dir := t.TempDir()
+	first := newTestBoltStore(dir)
+	assert.Nil(t, first.Set("flag/darkMode", "on"))
+	assert.Nil(t, first.Set("flag/beta", "on"))
+	assert.Nil(t, first.Persist())
+
+	// another process changes one key, then this store changes the other one. As only the key this
+	// store changed is written, the other process's change is kept.
+	second := newTestBoltStore(dir)
+	assert.Nil(t, second.Load())
+	assert.Nil(t, second.Set("flag/darkMode", "off"))
+	assert.Nil(t, second.Persist())
+
+	assert.Nil(t, first.Set("flag/beta", "off"))
+	assert.Nil(t, first.Persist())
+	assert.Equal(t, map[string]string{"flag/darkMode": `"off"`, "flag/beta": `"off"`}, boltKeys(t, dir))
+
+	t.Run("Reset only writes keys that were added, changed or removed", func(t *testing.T) {
+		// first still holds darkMode as "on", as Reset doesn't change it the value isn't written back over
+		// the one written by the other process
+		assert.Nil(t, first.Reset(map[string]interface{}{"flag/darkMode": "on", "flag/new": "on"}, true))
+		assert.Equal(t, map[string]string{"flag/darkMode": `"off"`, "flag/new": `"on"`}, boltKeys(t, dir))
+	})
+}
+
+func TestBoltStore_ConcurrentReaders(t *testing.T) {
+	dir := t.TempDir()
+	writer := newTestBoltStore(dir)
+	assert.Nil(t, writer.Reset(map[string]interface{}{"a": float64(0), "b": float64(0)}, true))
+
+	// each store opens the database separately, as separate processes would, so they contend on the file
+	// lock rather than sharing a handle
+	const versions = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 1; i <= versions; i++ {
+			if err := writer.Reset(map[string]interface{}{"a": float64(i), "b": float64(i)}, true); err != nil {
+				errs <- err
+				return
+			}
+		}
+	}()
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < versions; i++ {
+				reader := newTestBoltStore(dir)
+				if err := reader.Load(); err != nil {
+					errs <- err
+					return
+				}
+				// both keys are written in the same transaction, so a reader never sees them out of step
+				a, _ := reader.Get("a")
+				b, _ := reader.Get("b")
+				if a != b {
+					errs <- fmt.Errorf("read a=%v b=%v", a, b)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.Nil(t, err)
+	}
+
+	reader := newTestBoltStore(dir)
+	assert.Nil(t, reader.Load())
+	a, _ := reader.Get("a")
+	assert.Equal(t, float64(versions), a)
+}
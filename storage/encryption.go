@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrDecryptionFailed is returned when stored data can't be decrypted with any of the keys. The data's
+// checksum is verified first, so this means the data was encrypted with a different key.
+var ErrDecryptionFailed = errors.New("unable to decrypt stored data")
+
+// ErrKeyUnavailable is returned when the KeyProvider fails to supply a key
+var ErrKeyUnavailable = errors.New("encryption key unavailable")
+
+// ErrNotEncrypted is returned when an encrypted store finds data that isn't encrypted
+var ErrNotEncrypted = errors.New("stored data is not encrypted")
+
+// KeyProvider supplies the key used to encrypt and decrypt stored data. The key must be 16, 24 or 32
+// bytes long to select AES-128, AES-192 or AES-256. Key is called every time the data is loaded or
+// persisted, so implementations can fetch the key from a secret manager. To rotate the key without
+// losing the stored data, implement KeyRing as well.
+type KeyProvider interface {
+	Key() ([]byte, error)
+}
+
+// KeyRing is a KeyProvider that can also decrypt data written with previous keys. Data is always
+// encrypted with Key, so it's re-encrypted with the new key the next time it's persisted.
+type KeyRing interface {
+	KeyProvider
+	// DecryptionKeys returns the keys to try when decrypting, in order
+	DecryptionKeys() ([][]byte, error)
+}
+
+// StaticKey is a KeyProvider that always returns the same key
+type StaticKey []byte
+
+// Key returns the key
+func (k StaticKey) Key() ([]byte, error) {
+	return k, nil
+}
+
+// StaticKeyRing is a KeyRing with fixed keys
+type StaticKeyRing struct {
+	// Current is used to encrypt the data, and is tried first when decrypting
+	Current []byte
+	// Previous are tried in order when the data can't be decrypted with Current
+	Previous [][]byte
+}
+
+// Key returns the current key
+func (k StaticKeyRing) Key() ([]byte, error) {
+	return k.Current, nil
+}
+
+// DecryptionKeys returns the current key followed by the previous keys
+func (k StaticKeyRing) DecryptionKeys() ([][]byte, error) {
+	return append([][]byte{k.Current}, k.Previous...), nil
+}
+
+func newGCM(keys KeyProvider) (cipher.AEAD, error) {
+	key, err := keys.Key()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrKeyUnavailable, err)
+	}
+	return newGCMWithKey(key)
+}
+
+func newGCMWithKey(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encrypt seals plaintext with AES-GCM, the random nonce is prepended to the ciphertext
+func encrypt(keys KeyProvider, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(keys)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// decryptionKeys returns the keys to try when decrypting
+func decryptionKeys(keys KeyProvider) ([][]byte, error) {
+	if ring, ok := keys.(KeyRing); ok {
+		decryptionKeys, err := ring.DecryptionKeys()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrKeyUnavailable, err)
+		}
+		return decryptionKeys, nil
+	}
+	key, err := keys.Key()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrKeyUnavailable, err)
+	}
+	return [][]byte{key}, nil
+}
+
+// decrypt opens ciphertext produced by encrypt, trying each of the decryption keys in turn
+func decrypt(keys KeyProvider, ciphertext []byte) ([]byte, error) {
+	candidates, err := decryptionKeys(keys)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range candidates {
+		gcm, err := newGCMWithKey(key)
+		if err != nil {
+			return nil, err
+		}
+		if len(ciphertext) < gcm.NonceSize() {
+			return nil, ErrDecryptionFailed
+		}
+		nonce, sealed := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+		if plaintext, err := gcm.Open(nil, nonce, sealed, nil); err == nil {
+			return plaintext, nil
+		}
+	}
+	return nil, ErrDecryptionFailed
+}
@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harness/ff-golang-server-sdk/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	oldKey = []byte("0123456789abcdef0123456789abcdef")
+	newKey = []byte("fedcba9876543210fedcba9876543210")
+)
+
+type failingKey struct{}
+
+func (failingKey) Key() ([]byte, error) {
+	return nil, errors.New("secret manager unavailable")
+}
+
+func persistEncrypted(t *testing.T, dir string, keys KeyProvider) {
+	store := NewEncryptedFileStore("test", dir, keys, logger.NewNoOpLogger())
+	assert.Nil(t, store.Set("flag/darkMode", "on"))
+	assert.Nil(t, store.Persist())
+}
+
+func TestEncryptedFileStore(t *testing.T) {
+	tests := []struct {
+		name      string
+		keys      KeyProvider
+		expectErr error
+	}{
+		{name: "Same key", keys: StaticKey(oldKey)},
+		{name: "Wrong key", keys: StaticKey(newKey), expectErr: ErrDecryptionFailed},
+		{name: "Rotated key", keys: StaticKeyRing{Current: newKey, Previous: [][]byte{oldKey}}},
+		{name: "Rotated key without the previous key", keys: StaticKeyRing{Current: newKey}, expectErr: ErrDecryptionFailed},
+		{name: "Key unavailable", keys: failingKey{}, expectErr: ErrKeyUnavailable},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			persistEncrypted(t, dir, StaticKey(oldKey))
+
+			store := NewEncryptedFileStore("test", dir, tc.keys, logger.NewNoOpLogger())
+			err := store.Load()
+			if tc.expectErr != nil {
+				assert.True(t, errors.Is(err, tc.expectErr), err)
+				_, ok := store.Get("flag/darkMode")
+				assert.False(t, ok)
+			} else {
+				assert.Nil(t, err)
+				value, ok := store.Get("flag/darkMode")
+				assert.True(t, ok)
+				assert.Equal(t, "on", value)
+			}
+			// the file is never treated as corrupt because of the key
+			assert.Empty(t, corruptFiles(t, dir))
+		})
+	}
+}
+
+func TestEncryptedFileStore_RotatedKeyReencrypts(t *testing.T) {
+	dir := t.TempDir()
+	persistEncrypted(t, dir, StaticKey(oldKey))
+
+	rotated := NewEncryptedFileStore("test", dir, StaticKeyRing{Current: newKey, Previous: [][]byte{oldKey}}, logger.NewNoOpLogger())
+	assert.Nil(t, rotated.Load())
+	assert.Nil(t, rotated.Persist())
+
+	store := NewEncryptedFileStore("test", dir, StaticKey(newKey), logger.NewNoOpLogger())
+	assert.Nil(t, store.Load())
+	value, ok := store.Get("flag/darkMode")
+	assert.True(t, ok)
+	assert.Equal(t, "on", value)
+}
+
+func TestEncryptedFileStore_RejectsPlaintext(t *testing.T) {
+	dir := t.TempDir()
+	plain := NewFileStore("test", dir, logger.NewNoOpLogger())
+	assert.Nil(t, plain.Set("flag/darkMode", "on"))
+	assert.Nil(t, plain.Persist())
+
+	store := NewEncryptedFileStore("test", dir, StaticKey(oldKey), logger.NewNoOpLogger())
+	assert.True(t, errors.Is(store.Load(), ErrNotEncrypted))
+	_, ok := store.Get("flag/darkMode")
+	assert.False(t, ok)
+
+	// the plaintext file is replaced with an encrypted one the next time the data is persisted
+	assert.Nil(t, store.Set("flag/darkMode", "off"))
+	assert.Nil(t, store.Persist())
+	content, err := os.ReadFile(filepath.Join(dir, "harness-ffm-v1-test.json"))
+	assert.Nil(t, err)
+	assert.False(t, strings.Contains(string(content), "darkMode"))
+	assert.Nil(t, store.Load())
+}
@@ -1,6 +1,8 @@
 package storage
 
 import (
+	"crypto/sha256"
+	"errors"
 	"fmt"
 	"sync"
 
@@ -21,6 +23,8 @@ type FileStore struct {
 	data          map[string]interface{}
 	lastPersisted time.Time
 	logger        logger.Logger
+	// keys is set if the data is encrypted
+	keys KeyProvider
 }
 
 // NewFileStore creates a new file store instance
@@ -33,59 +37,192 @@ func NewFileStore(project string, path string, logger logger.Logger) *FileStore
 	}
 }
 
+// NewEncryptedFileStore creates a new file store instance that encrypts the data with AES-GCM using the
+// key from keys. Files written by an unencrypted store aren't loaded, as anyone able to write the file could
+// change the flags, and are replaced with an encrypted file the next time the data is persisted.
+func NewEncryptedFileStore(project string, path string, keys KeyProvider, logger logger.Logger) *FileStore {
+	store := NewFileStore(project, path, logger)
+	store.keys = keys
+	return store
+}
+
 var json = jsoniter.ConfigCompatibleWithStandardLibrary
 
+// fileSchemaVersion is written to every file so that the format can be changed later, files without
+// a version were written before the data was wrapped and are read as plain data
+const fileSchemaVersion = 1
+
+// fileEnvelope is the layout of the file, the checksum covers the encoded data so that a file that was
+// truncated or modified can be detected. Encrypted data is stored as a base64 string.
+type fileEnvelope struct {
+	Version   int                 `json:"version"`
+	Checksum  string              `json:"checksum"`
+	Encrypted bool                `json:"encrypted,omitempty"`
+	Data      jsoniter.RawMessage `json:"data"`
+}
+
+func checksum(data []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
+
 // Reset data with custom value, if persist is true save it to the store
 func (ds *FileStore) Reset(data map[string]interface{}, persist bool) error {
+	ds.mu.Lock()
 	ds.data = data
+	ds.mu.Unlock()
 	if persist {
 		return ds.Persist()
 	}
 	return nil
 }
 
-// Load data from the store
+// Load data from the store. A file that can't be decoded or fails its checksum is moved aside and the
+// store starts empty, so a crash while writing doesn't stop the data being rebuilt. A file that can't be
+// decrypted with the available keys, or isn't encrypted when it should be, is left in place and an error
+// is returned.
 func (ds *FileStore) Load() error {
-	file, err := os.Open(ds.path)
+	content, err := os.ReadFile(ds.path)
 	if err != nil {
 		return err
 	}
-	defer func() {
-		if file != nil {
-			if err := file.Close(); err != nil {
-				ds.logger.Errorf("error closing file, err: %v", err)
-			}
+
+	data, err := ds.decodeFile(content)
+	// a missing or wrong key doesn't mean the file is corrupt, it may be readable once the key is available
+	if errors.Is(err, ErrKeyUnavailable) || errors.Is(err, ErrDecryptionFailed) || errors.Is(err, ErrNotEncrypted) {
+		return err
+	}
+	if err != nil {
+		ds.quarantine(err)
+		data = make(map[string]interface{})
+	}
+
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.data = data
+	return nil
+}
+
+func (ds *FileStore) decodeFile(content []byte) (map[string]interface{}, error) {
+	var envelope fileEnvelope
+	if err := json.Unmarshal(content, &envelope); err != nil {
+		return nil, err
+	}
+
+	raw := []byte(envelope.Data)
+	switch envelope.Version {
+	case 0:
+		raw = content
+	case fileSchemaVersion:
+		if checksum(raw) != envelope.Checksum {
+			return nil, fmt.Errorf("checksum mismatch")
 		}
-	}()
+	default:
+		return nil, fmt.Errorf("unsupported schema version %d", envelope.Version)
+	}
+
+	if !envelope.Encrypted && ds.keys != nil {
+		return nil, ErrNotEncrypted
+	}
+	if envelope.Encrypted {
+		if ds.keys == nil {
+			return nil, fmt.Errorf("%w: no key was provided", ErrDecryptionFailed)
+		}
+		var ciphertext []byte
+		if err := json.Unmarshal(raw, &ciphertext); err != nil {
+			return nil, err
+		}
+		plaintext, err := decrypt(ds.keys, ciphertext)
+		if err != nil {
+			return nil, err
+		}
+		raw = plaintext
+	}
+
+	data := make(map[string]interface{})
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// quarantine renames a corrupt file so it can be inspected later without being loaded again
+func (ds *FileStore) quarantine(cause error) {
+	corruptPath := fmt.Sprintf("%s.corrupt-%d", ds.path, ds.getTime().Unix())
+	if err := os.Rename(ds.path, corruptPath); err != nil {
+		ds.logger.Errorf("error quarantining corrupt file %s, err: %v", ds.path, err)
+		return
+	}
+	ds.logger.Warnf("file %s is corrupt and has been moved to %s, starting with empty data, err: %v", ds.path, corruptPath, cause)
+}
+
+// Persist data to the store. The data is written to a temporary file that is synced and then renamed
+// over the previous file, so the file is either the previous version or the new one, never partial.
+func (ds *FileStore) Persist() error {
 	ds.mu.Lock()
 	defer ds.mu.Unlock()
-	dec := json.NewDecoder(file)
-	if err := dec.Decode(&ds.data); err != nil {
+
+	data, err := json.Marshal(ds.data)
+	if err != nil {
 		return err
 	}
+	if ds.keys != nil {
+		ciphertext, err := encrypt(ds.keys, data)
+		if err != nil {
+			return err
+		}
+		if data, err = json.Marshal(ciphertext); err != nil {
+			return err
+		}
+	}
+	content, err := json.Marshal(fileEnvelope{
+		Version:   fileSchemaVersion,
+		Checksum:  checksum(data),
+		Encrypted: ds.keys != nil,
+		Data:      data,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(ds.path, content); err != nil {
+		return err
+	}
+	ds.lastPersisted = ds.getTime()
 	return nil
 }
 
-// Persist data to the store
-func (ds *FileStore) Persist() error {
-	file, err := os.Create(ds.path)
+func writeFileAtomic(path string, content []byte) error {
+	dir := filepath.Dir(path)
+	file, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
 	if err != nil {
 		return err
 	}
+	tmpPath := file.Name()
+	// the temp file is only left behind if something failed before the rename
 	defer func() {
-		if file != nil {
-			if err := file.Close(); err != nil {
-				ds.logger.Errorf("error closing file, err: %v", err)
-			}
-		}
+		_ = os.Remove(tmpPath)
 	}()
-	enc := json.NewEncoder(file)
-	ds.mu.Lock()
-	defer ds.mu.Unlock()
-	if err := enc.Encode(ds.data); err != nil {
+
+	if _, err := file.Write(content); err != nil {
+		_ = file.Close()
 		return err
 	}
-	ds.lastPersisted = ds.getTime()
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+
+	// sync the directory so the rename itself survives a crash, not every platform supports this
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		_ = d.Close()
+	}
 	return nil
 }
 
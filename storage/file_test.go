@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harness/ff-golang-server-sdk/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func corruptFiles(t *testing.T, dir string) []string {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.corrupt-*"))
+	assert.Nil(t, err)
+	return matches
+}
+
+func TestFileStore_PersistAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileStore("test", dir, logger.NewNoOpLogger())
+	assert.Nil(t, store.Set("flag/darkMode", "on"))
+	assert.Nil(t, store.Persist())
+
+	content, err := os.ReadFile(filepath.Join(dir, "harness-ffm-v1-test.json"))
+	assert.Nil(t, err)
+	var envelope fileEnvelope
+	assert.Nil(t, json.Unmarshal(content, &envelope))
+	assert.Equal(t, fileSchemaVersion, envelope.Version)
+	assert.Equal(t, checksum(envelope.Data), envelope.Checksum)
+	assert.False(t, envelope.Encrypted)
+
+	loaded := NewFileStore("test", dir, logger.NewNoOpLogger())
+	assert.Nil(t, loaded.Load())
+	value, ok := loaded.Get("flag/darkMode")
+	assert.True(t, ok)
+	assert.Equal(t, "on", value)
+}
+
+func TestFileStore_LoadChecksumMismatch(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileStore("test", dir, logger.NewNoOpLogger())
+	assert.Nil(t, store.Set("flag/darkMode", "on"))
+	assert.Nil(t, store.Persist())
+
+	// the file is still valid JSON, but the data no longer matches the checksum
+	path := filepath.Join(dir, "harness-ffm-v1-test.json")
+	content, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Nil(t, os.WriteFile(path, []byte(strings.Replace(string(content), `"on"`, `"off"`, 1)), 0600))
+
+	loaded := NewFileStore("test", dir, logger.NewNoOpLogger())
+	assert.Nil(t, loaded.Load())
+	_, ok := loaded.Get("flag/darkMode")
+	assert.False(t, ok)
+
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+	assert.Len(t, corruptFiles(t, dir), 1)
+}
+
+func TestFileStore_LoadLegacyFile(t *testing.T) {
+	dir := t.TempDir()
+	// files written before the schema version was added hold the data directly
+	path := filepath.Join(dir, "harness-ffm-v1-test.json")
+	assert.Nil(t, os.WriteFile(path, []byte(`{"flag/darkMode":"on"}`), 0600))
+
+	store := NewFileStore("test", dir, logger.NewNoOpLogger())
+	assert.Nil(t, store.Load())
+	value, ok := store.Get("flag/darkMode")
+	assert.True(t, ok)
+	assert.Equal(t, "on", value)
+	assert.Empty(t, corruptFiles(t, dir))
+
+	// and are rewritten with the current version the next time they're persisted
+	assert.Nil(t, store.Persist())
+	content, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	var envelope fileEnvelope
+	assert.Nil(t, json.Unmarshal(content, &envelope))
+	assert.Equal(t, fileSchemaVersion, envelope.Version)
+}
+
+func TestFileStore_LoadUnsupportedVersion(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "harness-ffm-v1-test.json")
+	assert.Nil(t, os.WriteFile(path, []byte(`{"version":2,"checksum":"","data":{}}`), 0600))
+
+	store := NewFileStore("test", dir, logger.NewNoOpLogger())
+	assert.Nil(t, store.Load())
+	assert.Empty(t, store.List())
+	assert.Len(t, corruptFiles(t, dir), 1)
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.json")
+
+	assert.Nil(t, writeFileAtomic(path, []byte("first version, which is longer")))
+	assert.Nil(t, writeFileAtomic(path, []byte("second")))
+	content, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "second", string(content))
+
+	entries, err := os.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+
+	t.Run("A failed write leaves the previous file and no temporary file", func(t *testing.T) {
+		// the rename fails as the destination is a non-empty directory
+		target := filepath.Join(dir, "target")
+		assert.Nil(t, os.MkdirAll(filepath.Join(target, "child"), 0700))
+		assert.NotNil(t, writeFileAtomic(target, []byte("content")))
+
+		temps, err := filepath.Glob(filepath.Join(dir, "target.tmp-*"))
+		assert.Nil(t, err)
+		assert.Empty(t, temps)
+		info, err := os.Stat(target)
+		assert.Nil(t, err)
+		assert.True(t, info.IsDir())
+	})
+}
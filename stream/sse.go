@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/harness/ff-golang-server-sdk/apiconfig"
@@ -13,6 +14,7 @@ import (
 
 	"github.com/cenkalti/backoff/v4"
 	"github.com/harness/ff-golang-server-sdk/dto"
+	"github.com/harness/ff-golang-server-sdk/evaluation"
 	"github.com/harness/ff-golang-server-sdk/logger"
 	"github.com/harness/ff-golang-server-sdk/rest"
 
@@ -31,6 +33,7 @@ type SSEClient struct {
 	streamDisconnected  chan error
 	apiConfig           apiconfig.ApiConfiguration
 	proxyMode           bool
+	wg                  sync.WaitGroup
 }
 
 var json = jsoniter.ConfigCompatibleWithStandardLibrary
@@ -68,13 +71,36 @@ func NewSSEClient(
 
 // Connect will subscribe to SSE stream
 func (c *SSEClient) Connect(ctx context.Context, environment string, apiKey string) {
-	go func() {
+	c.spawn(func() {
 		for event := range orDone(ctx, c.subscribe(ctx, environment, apiKey)) {
 			c.handleEvent(event)
 		}
+	})
+}
+
+// Wait blocks until the goroutines started by Connect have exited, which happens once the stream disconnects
+// or the context passed to Connect is done
+func (c *SSEClient) Wait() {
+	c.wg.Wait()
+}
+
+// spawn runs fn in a goroutine that Wait waits for
+func (c *SSEClient) spawn(fn func()) {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		fn()
 	}()
 }
 
+// disconnected reports that the stream has disconnected, unless ctx is done and nothing is listening anymore
+func (c *SSEClient) disconnected(ctx context.Context, err error) {
+	select {
+	case c.streamDisconnected <- err:
+	case <-ctx.Done():
+	}
+}
+
 // Connect will subscribe to SSE stream
 func (c *SSEClient) subscribe(ctx context.Context, environment string, apiKey string) <-chan Event {
 	c.logger.Info("Attempting to start stream")
@@ -92,27 +118,30 @@ func (c *SSEClient) subscribe(ctx context.Context, environment string, apiKey st
 	onConnect := func(s *sse.Client) {
 		// Start the dead stream timer
 		deadStreamTimer.Reset(timeout)
-		c.streamConnected <- struct{}{}
+		select {
+		case c.streamConnected <- struct{}{}:
+		case <-ctx.Done():
+		}
 	}
 	c.client.OnConnect(onConnect)
 	out := make(chan Event)
-	go func() {
+	c.spawn(func() {
 		defer close(out)
 
 		// Create another context off of the main SDK context, so we can close dead streams.
 		deadStreamCtx, deadStreamCancel := context.WithCancel(ctx)
 		defer deadStreamCancel()
 
-		go func() {
+		c.spawn(func() {
 			select {
-			case <-ctx.Done():
+			case <-deadStreamCtx.Done():
 				return
 			case <-deadStreamTimer.C:
 				deadStreamTimer.Stop()
 				deadStreamCancel()
 				return
 			}
-		}()
+		})
 
 		err := c.client.SubscribeWithContext(deadStreamCtx, "*", func(msg *sse.Event) {
 
@@ -143,7 +172,7 @@ func (c *SSEClient) subscribe(ctx context.Context, environment string, apiKey st
 		})
 		if err != nil {
 			deadStreamTimer.Stop()
-			c.streamDisconnected <- err
+			c.disconnected(ctx, err)
 			return
 		}
 
@@ -152,7 +181,7 @@ func (c *SSEClient) subscribe(ctx context.Context, environment string, apiKey st
 		// When we cancel the deadStreamContext, we exit the `SubscribeWithContext` function with a nil error.
 		// So we need an explicit check to see if the reason was
 		if errors.Is(deadStreamCtx.Err(), context.Canceled) {
-			c.streamDisconnected <- fmt.Errorf("no SSE events received for 30 seconds. Assuming stream is dead and restarting")
+			c.disconnected(ctx, fmt.Errorf("no SSE events received for 30 seconds. Assuming stream is dead and restarting"))
 			return
 		}
 
@@ -161,12 +190,19 @@ func (c *SSEClient) subscribe(ctx context.Context, environment string, apiKey st
 		// So we need to signal the stream disconnected channel any time we've exited SubscribeWithContext.
 		// If we don't do this and the server closes the connection the Go SDK will still think it's connected to the stream
 		// even though it isn't.
-		c.streamDisconnected <- fmt.Errorf("server closed the connection")
-	}()
+		c.disconnected(ctx, fmt.Errorf("server closed the connection"))
+	})
 
 	return out
 }
 
+// validatePrerequisites logs updated flags whose prerequisites form a cycle or are nested too deeply
+func (c *SSEClient) validatePrerequisites(flags ...rest.FeatureConfig) {
+	for _, err := range evaluation.ValidatePrerequisites(c.repository.GetFlag, flags...) {
+		c.logger.Warnf("Invalid flag prerequisites, the off variation will be served: %s", err)
+	}
+}
+
 func (c *SSEClient) handleEvent(event Event) {
 	cfMsg := Message{}
 	err := json.Unmarshal(event.SSEEvent.Data, &cfMsg)
@@ -198,6 +234,7 @@ func (c *SSEClient) handleEvent(event Event) {
 
 				if response.JSON200 != nil {
 					c.repository.SetFlag(*response.JSON200, false)
+					c.validatePrerequisites(*response.JSON200)
 				}
 			}
 			updateWithTimeout()
@@ -215,6 +252,7 @@ func (c *SSEClient) handleEvent(event Event) {
 
 					if response.JSON200 != nil {
 						c.repository.SetFlags(false, event.Environment, *response.JSON200...)
+						c.validatePrerequisites(*response.JSON200...)
 					}
 				}
 				updateFeaturesWithTimeout()
@@ -11,6 +11,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/harness/ff-golang-server-sdk/cache"
 	"github.com/harness/ff-golang-server-sdk/logger"
 
 	"github.com/harness/ff-golang-server-sdk/evaluation"
@@ -20,7 +21,11 @@ import (
 	"github.com/harness/ff-golang-server-sdk/rest"
 )
 
-const source = "./ff-test-cases/tests"
+const (
+	source = "./ff-test-cases/tests"
+	// localSource holds test cases for evaluator behaviour that isn't covered by ff-test-cases yet
+	localSource = "./testdata"
+)
 
 type test struct {
 	Flag     string      `json:"flag"`
@@ -37,9 +42,17 @@ type testFile struct {
 }
 
 func loadFiles() []testFile {
+	slice := []testFile{}
+	for _, dir := range []string{source, localSource} {
+		slice = append(slice, loadDir(dir)...)
+	}
+	return slice
+}
+
+func loadDir(dir string) []testFile {
 
 	slice := []testFile{}
-	err := filepath.Walk(source,
+	err := filepath.Walk(dir,
 		func(path string, info os.FileInfo, err error) error {
 			if err != nil {
 				return err
@@ -84,7 +97,7 @@ func TestEvaluator(t *testing.T) {
 	t.Parallel()
 	fixtures := loadFiles()
 	for _, fixture := range fixtures {
-		lruCache, err := repository.NewLruCache(1000)
+		lruCache, err := cache.NewLruCache(1000, logger.NewNoOpLogger())
 		if err != nil {
 			t.Error(err)
 		}
@@ -24,12 +24,12 @@ func NewNumber(value interface{}) (*Number, error) {
 // we ignore any additional elements if they exist.
 func numberOperator(value []string, fn func(float64) bool) bool {
 	if len(value) > 0 {
-		if i, err := strconv.ParseFloat(value[0], 64); err == nil {
-			if fn(i) {
-				return true
-			}
+		i, err := strconv.ParseFloat(value[0], 64)
+		if err != nil {
+			log.Warnf("input contains invalid value for number comparisons: %s\n", value)
+			return false
 		}
-		log.Warnf("input contains invalid value for number comparisons: %s\n", value)
+		return fn(i)
 	}
 	return false
 }